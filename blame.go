@@ -0,0 +1,279 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LineRange restricts a blame to a span of lines in the final image of the
+// file, 1-indexed and inclusive, matching `git blame -L Start,End`.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// BlameOptions controls how Blame walks line history.
+type BlameOptions struct {
+	// IgnoreWhitespace maps to `-w`: ignore whitespace-only changes when
+	// attributing a line to a commit.
+	IgnoreWhitespace bool
+	// DetectMovedLines maps to `-M -C`: detect lines moved or copied from
+	// other files in the same commit.
+	DetectMovedLines bool
+	// Range restricts the blame to a span of lines, matching `-L`. The
+	// zero value blames the whole file.
+	Range LineRange
+}
+
+// BlameLine is the attribution of a single line of a blamed file.
+type BlameLine struct {
+	CommitSHA      string
+	AuthorName     string
+	AuthorEmail    string
+	AuthorTime     time.Time
+	CommitterName  string
+	CommitterEmail string
+	CommitterTime  time.Time
+	Summary        string
+	PreviousSHA    string
+	PreviousPath   string
+	OriginalLine   int
+	FinalLine      int
+	Content        string
+}
+
+// BlameResult is the per-line authorship of a file at a revision.
+type BlameResult struct {
+	Path  string
+	Lines []BlameLine
+}
+
+// Blame runs `git blame --porcelain --incremental <rev> -- <path>` and
+// returns the per-line authorship of path as of rev.
+func (c CLI) Blame(rev, path string) (*BlameResult, error) {
+	return c.BlameWithOptions(rev, path, BlameOptions{})
+}
+
+// BlameWithOptions is Blame with control over whitespace handling, moved/
+// copied line detection, and the line range to blame.
+func (c CLI) BlameWithOptions(rev, path string, opts BlameOptions) (*BlameResult, error) {
+	args := []string{"blame", "--porcelain", "--incremental"}
+	if opts.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	if opts.DetectMovedLines {
+		args = append(args, "-M", "-C")
+	}
+	if opts.Range.Start > 0 || opts.Range.End > 0 {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", opts.Range.Start, opts.Range.End))
+	}
+	args = append(args, rev, "--", path)
+
+	buf, err := c.runCommandAndReadOutputAsBytes(args[0], args[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := parseBlameIncremental(buf)
+	if err != nil {
+		return nil, err
+	}
+	// --incremental emits hunks in the order git discovers them while
+	// walking history, not in line order; restore line order for callers.
+	sort.Slice(lines, func(i, j int) bool { return lines[i].FinalLine < lines[j].FinalLine })
+
+	if err := c.fillBlameContent(rev, path, lines); err != nil {
+		return nil, err
+	}
+
+	return &BlameResult{Path: path, Lines: lines}, nil
+}
+
+// fillBlameContent attaches the source text of each line. --incremental
+// porcelain output carries attribution only, not file content, so the blob
+// is fetched once and lines are matched up by final line number.
+func (c CLI) fillBlameContent(rev, path string, lines []BlameLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	blob, err := c.Blob(rev, path)
+	if err != nil {
+		return err
+	}
+	blobLines := strings.Split(blob, "\n")
+
+	for i := range lines {
+		idx := lines[i].FinalLine - 1
+		if idx >= 0 && idx < len(blobLines) {
+			lines[i].Content = blobLines[idx]
+		}
+	}
+	return nil
+}
+
+// parseBlameIncremental parses the output of `git blame --porcelain
+// --incremental`: a sequence of "<sha> <origLine> <finalLine> <count>"
+// headers, each followed by zero or more key/value metadata lines. Commit
+// metadata (author, committer, summary, previous) is only emitted the first
+// time a given SHA is seen, so it is cached and reused across later hunks
+// that share it.
+func parseBlameIncremental(buf []byte) ([]BlameLine, error) {
+	type commitMeta struct {
+		authorName     string
+		authorEmail    string
+		authorTime     time.Time
+		committerName  string
+		committerEmail string
+		committerTime  time.Time
+		summary        string
+		previousSHA    string
+		previousPath   string
+	}
+
+	metaCache := map[string]*commitMeta{}
+	var lines []BlameLine
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	type group struct {
+		sha       string
+		origLine  int
+		finalLine int
+		count     int
+	}
+	var cur *group
+	var meta *commitMeta
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		for i := 0; i < cur.count; i++ {
+			l := BlameLine{
+				CommitSHA:    cur.sha,
+				OriginalLine: cur.origLine + i,
+				FinalLine:    cur.finalLine + i,
+			}
+			if meta != nil {
+				l.AuthorName = meta.authorName
+				l.AuthorEmail = meta.authorEmail
+				l.AuthorTime = meta.authorTime
+				l.CommitterName = meta.committerName
+				l.CommitterEmail = meta.committerEmail
+				l.CommitterTime = meta.committerTime
+				l.Summary = meta.summary
+				l.PreviousSHA = meta.previousSHA
+				l.PreviousPath = meta.previousPath
+			}
+			lines = append(lines, l)
+		}
+		cur = nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		if isBlameHeader(line) {
+			flush()
+			fields := strings.Fields(line)
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("malformed blame header: %q", line)
+			}
+			origLine, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("malformed blame header %q: %w", line, err)
+			}
+			finalLine, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("malformed blame header %q: %w", line, err)
+			}
+			count, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return nil, fmt.Errorf("malformed blame header %q: %w", line, err)
+			}
+			sha := fields[0]
+			cur = &group{sha: sha, origLine: origLine, finalLine: finalLine, count: count}
+			meta = metaCache[sha]
+			if meta == nil {
+				meta = &commitMeta{}
+				metaCache[sha] = meta
+			}
+			continue
+		}
+
+		if cur == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "author-mail "):
+			meta.authorEmail = strings.Trim(strings.TrimPrefix(line, "author-mail "), "<>")
+		case strings.HasPrefix(line, "author-time "):
+			meta.authorTime = parseEpoch(strings.TrimPrefix(line, "author-time "))
+		case strings.HasPrefix(line, "author-tz "):
+			// The timezone offset is not kept: parseEpoch returns times in the local zone.
+		case strings.HasPrefix(line, "author "):
+			meta.authorName = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "committer-mail "):
+			meta.committerEmail = strings.Trim(strings.TrimPrefix(line, "committer-mail "), "<>")
+		case strings.HasPrefix(line, "committer-time "):
+			meta.committerTime = parseEpoch(strings.TrimPrefix(line, "committer-time "))
+		case strings.HasPrefix(line, "committer-tz "):
+		case strings.HasPrefix(line, "committer "):
+			meta.committerName = strings.TrimPrefix(line, "committer ")
+		case strings.HasPrefix(line, "summary "):
+			meta.summary = strings.TrimPrefix(line, "summary ")
+		case strings.HasPrefix(line, "previous "):
+			fields := strings.SplitN(strings.TrimPrefix(line, "previous "), " ", 2)
+			if len(fields) == 2 {
+				meta.previousSHA = fields[0]
+				meta.previousPath = fields[1]
+			}
+		case strings.HasPrefix(line, "filename "):
+			// The current path, same as the path we queried; nothing to capture.
+		case line == "boundary":
+			// No parent commit; nothing further to capture.
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return lines, nil
+}
+
+// isBlameHeader reports whether line is a "<sha> <origLine> <finalLine>
+// <count>" hunk header as opposed to a key/value metadata line.
+func isBlameHeader(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) != 4 {
+		return false
+	}
+	if len(fields[0]) != 40 {
+		return false
+	}
+	for _, r := range fields[0] {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseEpoch(s string) time.Time {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(n, 0)
+}
@@ -0,0 +1,56 @@
+package git
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlame(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("line1\nline2\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("line1\nline2 changed\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+
+	log, err := c.Log()
+	assert.NoError(t, err)
+
+	result, err := c.Blame(log[0], "readme.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "readme.md", result.Path)
+	assert.Len(t, result.Lines, 2)
+
+	assert.Equal(t, "line1", result.Lines[0].Content)
+	assert.Equal(t, "first", result.Lines[0].Summary)
+	assert.Equal(t, log[1], result.Lines[0].CommitSHA)
+
+	assert.Equal(t, "line2 changed", result.Lines[1].Content)
+	assert.Equal(t, "second", result.Lines[1].Summary)
+	assert.Equal(t, log[0], result.Lines[1].CommitSHA)
+}
+
+func TestBlameWithOptionsRange(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("line1\nline2\nline3\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	log, err := c.Log()
+	assert.NoError(t, err)
+
+	result, err := c.BlameWithOptions(log[0], "readme.md", BlameOptions{Range: LineRange{Start: 1, End: 2}})
+	assert.NoError(t, err)
+	assert.Len(t, result.Lines, 2)
+}
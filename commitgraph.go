@@ -0,0 +1,207 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// commitGraph is the parsed form of a version-1 .git/objects/info/commit-graph
+// file: a sorted table of commit ids plus, for each one, its tree id, up to
+// two direct parent positions (with overflow into an extra-edge list for
+// merges with more than two parents), and its committer timestamp. It lets
+// readCommit answer parent/tree lookups without opening and inflating the
+// commit object itself.
+type commitGraph struct {
+	hashLen    int
+	oidFanout  [256]uint32
+	oidLookup  []byte
+	commitData []byte
+	extraEdges []byte
+}
+
+const (
+	commitGraphSignature   = "CGPH"
+	commitGraphVersion     = 1
+	graphParentMissing     = 0x70000000
+	graphExtraEdgesNeeded  = 0x80000000
+	graphExtraEdgeLastMask = 0x80000000
+	graphParentMask        = 0x7fffffff
+)
+
+// loadCommitGraph reads and parses gitDir/objects/info/commit-graph. It
+// returns (nil, nil) if the file doesn't exist, since commit-graph
+// acceleration is optional: callers fall back to walking commit objects
+// directly.
+func loadCommitGraph(gitDir string) (*commitGraph, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(gitDir, "objects", "info", "commit-graph"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseCommitGraph(raw)
+}
+
+// parseCommitGraph decodes the commit-graph header and chunk table, then
+// extracts the OID Fanout, OID Lookup, Commit Data, and (if present) Extra
+// Edge List chunks.
+func parseCommitGraph(raw []byte) (*commitGraph, error) {
+	if len(raw) < 8 || string(raw[:4]) != commitGraphSignature {
+		return nil, fmt.Errorf("malformed commit-graph: bad signature")
+	}
+	version := raw[4]
+	hashVersion := raw[5]
+	chunkCount := int(raw[6])
+	if version != commitGraphVersion {
+		return nil, fmt.Errorf("unsupported commit-graph version %d", version)
+	}
+
+	hashLen := 20
+	if hashVersion == 2 {
+		hashLen = 32
+	}
+
+	type chunkEntry struct {
+		id     string
+		offset uint64
+	}
+	var chunks []chunkEntry
+	off := 8
+	for i := 0; i <= chunkCount; i++ {
+		if off+12 > len(raw) {
+			return nil, fmt.Errorf("malformed commit-graph: truncated chunk table")
+		}
+		id := string(raw[off : off+4])
+		offset := binary.BigEndian.Uint64(raw[off+4 : off+12])
+		chunks = append(chunks, chunkEntry{id: id, offset: offset})
+		off += 12
+	}
+
+	chunkData := map[string][]byte{}
+	for i := 0; i < len(chunks)-1; i++ {
+		start, end := chunks[i].offset, chunks[i+1].offset
+		if end > uint64(len(raw)) || start > end {
+			return nil, fmt.Errorf("malformed commit-graph: chunk %q out of range", chunks[i].id)
+		}
+		chunkData[chunks[i].id] = raw[start:end]
+	}
+
+	oidf, ok := chunkData["OIDF"]
+	if !ok || len(oidf) != 256*4 {
+		return nil, fmt.Errorf("malformed commit-graph: missing or malformed OIDF chunk")
+	}
+	oidl, ok := chunkData["OIDL"]
+	if !ok {
+		return nil, fmt.Errorf("malformed commit-graph: missing OIDL chunk")
+	}
+	cdat, ok := chunkData["CDAT"]
+	if !ok {
+		return nil, fmt.Errorf("malformed commit-graph: missing CDAT chunk")
+	}
+
+	g := &commitGraph{
+		hashLen:    hashLen,
+		oidLookup:  oidl,
+		commitData: cdat,
+		extraEdges: chunkData["EDGE"],
+	}
+	for i := 0; i < 256; i++ {
+		g.oidFanout[i] = binary.BigEndian.Uint32(oidf[i*4 : i*4+4])
+	}
+
+	return g, nil
+}
+
+// count returns the number of commits recorded in the graph.
+func (g *commitGraph) count() int {
+	return int(g.oidFanout[255])
+}
+
+// find returns the lookup-table position of sha, using the fanout table to
+// narrow to a single byte bucket and a binary search within it.
+func (g *commitGraph) find(sha []byte) (int, bool) {
+	first := sha[0]
+	lo := 0
+	if first > 0 {
+		lo = int(g.oidFanout[first-1])
+	}
+	hi := int(g.oidFanout[first])
+
+	idx := sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(g.oidAt(lo+i), sha) >= 0
+	})
+	idx += lo
+	if idx < hi && bytes.Equal(g.oidAt(idx), sha) {
+		return idx, true
+	}
+	return 0, false
+}
+
+func (g *commitGraph) oidAt(i int) []byte {
+	return g.oidLookup[i*g.hashLen : (i+1)*g.hashLen]
+}
+
+func (g *commitGraph) shaAt(i int) string {
+	return hex.EncodeToString(g.oidAt(i))
+}
+
+// commitAt decodes the Commit Data chunk entry at position i, returning the
+// commit's tree id, parent ids, and committer timestamp.
+func (g *commitGraph) commitAt(i int) (tree string, parents []string, committerTime int64) {
+	entryLen := g.hashLen + 16
+	entry := g.commitData[i*entryLen : (i+1)*entryLen]
+
+	tree = hex.EncodeToString(entry[:g.hashLen])
+
+	parent1 := binary.BigEndian.Uint32(entry[g.hashLen : g.hashLen+4])
+	parent2 := binary.BigEndian.Uint32(entry[g.hashLen+4 : g.hashLen+8])
+	dateAndGeneration := binary.BigEndian.Uint64(entry[g.hashLen+8 : g.hashLen+16])
+	committerTime = int64(dateAndGeneration & 0x3FFFFFFFF)
+
+	if parent1 != graphParentMissing {
+		parents = append(parents, g.shaAt(int(parent1&graphParentMask)))
+	}
+	if parent2 == graphParentMissing {
+		return tree, parents, committerTime
+	}
+	if parent2&graphExtraEdgesNeeded == 0 {
+		parents = append(parents, g.shaAt(int(parent2&graphParentMask)))
+		return tree, parents, committerTime
+	}
+
+	idx := int(parent2 & graphParentMask)
+	for {
+		edge := binary.BigEndian.Uint32(g.extraEdges[idx*4 : idx*4+4])
+		parents = append(parents, g.shaAt(int(edge&graphParentMask)))
+		if edge&graphExtraEdgeLastMask != 0 {
+			break
+		}
+		idx++
+	}
+
+	return tree, parents, committerTime
+}
+
+// parents looks up sha in the graph and returns its tree id, parent ids,
+// and committer timestamp. The second return value is false if sha is not
+// recorded in the graph, in which case the caller should fall back to
+// reading the commit object directly.
+func (g *commitGraph) parents(sha string) (tree string, parents []string, committerTime int64, ok bool) {
+	shaBytes, err := hex.DecodeString(sha)
+	if err != nil || len(shaBytes) != g.hashLen {
+		return "", nil, 0, false
+	}
+	idx, found := g.find(shaBytes)
+	if !found {
+		return "", nil, 0, false
+	}
+	tree, parents, committerTime = g.commitAt(idx)
+	return tree, parents, committerTime, true
+}
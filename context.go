@@ -0,0 +1,140 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// RunStream starts `git <args...>` in the repository and returns its
+// standard output as a pipe while the process is still running, instead of
+// buffering it all into memory first. The process is tied to ctx: cancelling
+// ctx kills the subprocess. Callers must read the returned ReadCloser to
+// completion and Close it; Close waits for the process to exit and reports
+// a non-nil error if it failed, including the captured stderr text.
+func (c CLI) RunStream(ctx context.Context, args ...string) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = c.path
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &streamReadCloser{
+		ReadCloser: stdout,
+		cmd:        cmd,
+		ctx:        ctx,
+		stderr:     &stderr,
+	}, nil
+}
+
+// streamReadCloser wraps a running command's stdout pipe so that Close waits
+// for the process to exit and surfaces its error, mirroring the error
+// handling of runCommandAndReadOutputAsBytes.
+type streamReadCloser struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	ctx    context.Context
+	stderr *bytes.Buffer
+}
+
+func (s *streamReadCloser) Close() error {
+	closeErr := s.ReadCloser.Close()
+	waitErr := s.cmd.Wait()
+	if waitErr != nil {
+		// ctx cancellation kills the subprocess, which surfaces here as a
+		// signal-killed *exec.ExitError with nothing on stderr. Report the
+		// cancellation itself rather than an empty, unactionable error.
+		if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			errorLines, err := readLines(s.stderr.Bytes())
+			if err != nil {
+				return err
+			}
+			if len(errorLines) == 0 {
+				return waitErr
+			}
+			return errors.New(strings.Join(errorLines, ";"))
+		}
+		return waitErr
+	}
+	return closeErr
+}
+
+// runCommandCtx runs a git command to completion, returning its parsed
+// output lines, but ties the subprocess to ctx so callers can cancel it.
+func (c CLI) runCommandCtx(ctx context.Context, command string, arg ...string) ([]string, error) {
+	stream, err := c.RunStream(ctx, append([]string{command}, arg...)...)
+	if err != nil {
+		return nil, err
+	}
+	buf, readErr := io.ReadAll(stream)
+	closeErr := stream.Close()
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return readLines(buf)
+}
+
+// StatusCtx is the cancellable, context-aware equivalent of Status.
+func (c CLI) StatusCtx(ctx context.Context) ([]StatusEntry, error) {
+	stream, err := c.RunStream(ctx, "status", "--porcelain=v2", "-z", "--branch", "--untracked-files=all", "--ignored=matching")
+	if err != nil {
+		return nil, err
+	}
+	buf, readErr := io.ReadAll(stream)
+	closeErr := stream.Close()
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	rs, err := parseStatusV2(buf)
+	if err != nil {
+		return nil, err
+	}
+	return statusEntriesFromV2(rs), nil
+}
+
+// LogCtx is the cancellable, context-aware equivalent of Log.
+func (c CLI) LogCtx(ctx context.Context) ([]string, error) {
+	return c.runCommandCtx(ctx, "log", "--pretty=%H")
+}
+
+// DiffCtx is the cancellable, context-aware equivalent of Diff.
+func (c CLI) DiffCtx(ctx context.Context, from, to string) ([]string, error) {
+	return c.runCommandCtx(ctx, "diff-tree", "--no-commit-id", "-r", "--name-only", from, to)
+}
+
+// BlobCtx streams the content of <rev>:<path> instead of buffering it into a
+// string, so that callers reading a large blob can cancel the read (e.g. an
+// HTTP request dropping) without waiting for the whole object.
+func (c CLI) BlobCtx(ctx context.Context, sha, path string) (io.ReadCloser, error) {
+	return c.RunStream(ctx, "show", fmt.Sprintf("%s:%s", sha, path))
+}
+
+// LsTreeCtx streams the recursive, name-only tree listing for sha instead of
+// buffering it into a slice, for repositories with very large trees.
+func (c CLI) LsTreeCtx(ctx context.Context, sha string) (io.ReadCloser, error) {
+	return c.RunStream(ctx, "ls-tree", "--name-only", "-r", sha)
+}
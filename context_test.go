@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusCtx(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+
+	s, err := c.StatusCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "readme.md", s[0].Path)
+	assert.Equal(t, StatusUntracked, s[0].Status)
+}
+
+func TestBlobCtx(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	log, err := c.Log()
+	assert.NoError(t, err)
+
+	stream, err := c.BlobCtx(context.Background(), log[0], "readme.md")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(stream)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Close())
+	assert.Equal(t, "#hey", string(content))
+}
+
+func TestRunStreamCancel(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.runCommandCtx(ctx, "status", "-s")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,303 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LineKind identifies the kind of a line within a diff hunk
+type LineKind string
+
+const (
+	// LineContext is a line unchanged between the two sides of the diff
+	LineContext LineKind = "context"
+	// LineAdd is a line added on the "to" side of the diff
+	LineAdd LineKind = "add"
+	// LineDelete is a line removed from the "from" side of the diff
+	LineDelete LineKind = "delete"
+)
+
+// Line is a single line within a Hunk
+type Line struct {
+	Kind LineKind
+	Text string
+	// NoNewlineEOF is set when this line is immediately followed by git's
+	// "\ No newline at end of file" marker, i.e. this line is the last line
+	// of whichever side of the file it belongs to and that side has no
+	// trailing newline.
+	NoNewlineEOF bool
+}
+
+// Hunk is a contiguous block of changes within a FilePatch
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// FilePatch is the set of changes made to a single file
+type FilePatch struct {
+	OldPath      string
+	NewPath      string
+	OldMode      string
+	NewMode      string
+	Similarity   int
+	IsRename     bool
+	IsCopy       bool
+	IsBinary     bool
+	NoNewlineEOF bool
+	Hunks        []Hunk
+}
+
+// DiffPatch returns the parsed unified diff between two revisions. Empty
+// revisions are omitted from the `git diff` arguments: with both empty it
+// diffs the index against the workspace, and with only one given it diffs
+// that revision against the workspace.
+func (c CLI) DiffPatch(from, to string) ([]FilePatch, error) {
+	args := append([]string{"diff", "--unified=3", "--no-color"}, diffRevArgs(from, to)...)
+	buf, err := c.runCommandAndReadOutputAsBytes(args[0], args[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	return parsePatch(buf)
+}
+
+// DiffFile returns the parsed unified diff for a single path between two
+// revisions, with the same from/to conventions as DiffPatch.
+func (c CLI) DiffFile(from, to, path string) (*FilePatch, error) {
+	args := append([]string{"diff", "--unified=3", "--no-color"}, diffRevArgs(from, to)...)
+	args = append(args, "--", path)
+	buf, err := c.runCommandAndReadOutputAsBytes(args[0], args[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	patches, err := parsePatch(buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(patches) == 0 {
+		return nil, nil
+	}
+	return &patches[0], nil
+}
+
+// diffRevArgs builds the positional revision arguments for `git diff`,
+// omitting empty revisions so that e.g. DiffPatch("", "") diffs the
+// workspace against the index.
+func diffRevArgs(from, to string) []string {
+	var args []string
+	if from != "" {
+		args = append(args, from)
+	}
+	if to != "" {
+		args = append(args, to)
+	}
+	return args
+}
+
+var (
+	diffGitPrefix         = "diff --git "
+	indexPrefix           = "index "
+	oldModePrefix         = "old mode "
+	newModePrefix         = "new mode "
+	newFileModePrefix     = "new file mode "
+	deletedFileModePrefix = "deleted file mode "
+	similarityPrefix      = "similarity index "
+	renameFromPrefix      = "rename from "
+	renameToPrefix        = "rename to "
+	copyFromPrefix        = "copy from "
+	copyToPrefix          = "copy to "
+	minusPrefix           = "--- "
+	plusPrefix            = "+++ "
+	hunkHeaderPrefix      = "@@ "
+	binaryFilesMarker     = "Binary files "
+	noNewlineAtEOF        = "\\ No newline at end of file"
+	devNullPath           = "/dev/null"
+)
+
+// parsePatch parses the output of `git diff --unified=N --no-color` into a
+// slice of FilePatch values, one per file touched by the diff.
+func parsePatch(buf []byte) ([]FilePatch, error) {
+	var patches []FilePatch
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	var current *FilePatch
+	var hunk *Hunk
+
+	flushHunk := func() {
+		if current != nil && hunk != nil {
+			current.Hunks = append(current.Hunks, *hunk)
+			hunk = nil
+		}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, diffGitPrefix):
+			flushHunk()
+			if current != nil {
+				patches = append(patches, *current)
+			}
+			current = &FilePatch{}
+			a, b, ok := parseDiffGitHeader(line)
+			if ok {
+				current.OldPath = a
+				current.NewPath = b
+			}
+			continue
+		}
+
+		if current == nil {
+			// Stray output before the first "diff --git" header, ignore.
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, similarityPrefix):
+			sim := strings.TrimSuffix(strings.TrimPrefix(line, similarityPrefix), "%")
+			if n, err := strconv.Atoi(sim); err == nil {
+				current.Similarity = n
+			}
+		case strings.HasPrefix(line, renameFromPrefix):
+			current.IsRename = true
+			current.OldPath = strings.TrimPrefix(line, renameFromPrefix)
+		case strings.HasPrefix(line, renameToPrefix):
+			current.IsRename = true
+			current.NewPath = strings.TrimPrefix(line, renameToPrefix)
+		case strings.HasPrefix(line, copyFromPrefix):
+			current.IsCopy = true
+			current.OldPath = strings.TrimPrefix(line, copyFromPrefix)
+		case strings.HasPrefix(line, copyToPrefix):
+			current.IsCopy = true
+			current.NewPath = strings.TrimPrefix(line, copyToPrefix)
+		case strings.HasPrefix(line, oldModePrefix):
+			current.OldMode = strings.TrimPrefix(line, oldModePrefix)
+		case strings.HasPrefix(line, newModePrefix):
+			current.NewMode = strings.TrimPrefix(line, newModePrefix)
+		case strings.HasPrefix(line, newFileModePrefix):
+			current.NewMode = strings.TrimPrefix(line, newFileModePrefix)
+		case strings.HasPrefix(line, deletedFileModePrefix):
+			current.OldMode = strings.TrimPrefix(line, deletedFileModePrefix)
+		case strings.HasPrefix(line, indexPrefix):
+			// index <sha>..<sha> <mode> - mode is optional
+			fields := strings.Fields(strings.TrimPrefix(line, indexPrefix))
+			if len(fields) == 2 {
+				current.OldMode = fields[1]
+				current.NewMode = fields[1]
+			}
+		case strings.HasPrefix(line, binaryFilesMarker):
+			current.IsBinary = true
+		case strings.HasPrefix(line, minusPrefix):
+			flushHunk()
+			current.OldPath = trimDiffPath(strings.TrimPrefix(line, minusPrefix), "a/")
+		case strings.HasPrefix(line, plusPrefix):
+			flushHunk()
+			current.NewPath = trimDiffPath(strings.TrimPrefix(line, plusPrefix), "b/")
+		case strings.HasPrefix(line, hunkHeaderPrefix):
+			flushHunk()
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			hunk = h
+		case strings.HasPrefix(line, noNewlineAtEOF):
+			current.NoNewlineEOF = true
+			if hunk != nil && len(hunk.Lines) > 0 {
+				hunk.Lines[len(hunk.Lines)-1].NoNewlineEOF = true
+			}
+		case hunk != nil && strings.HasPrefix(line, "+"):
+			hunk.Lines = append(hunk.Lines, Line{Kind: LineAdd, Text: line[1:]})
+		case hunk != nil && strings.HasPrefix(line, "-"):
+			hunk.Lines = append(hunk.Lines, Line{Kind: LineDelete, Text: line[1:]})
+		case hunk != nil && strings.HasPrefix(line, " "):
+			hunk.Lines = append(hunk.Lines, Line{Kind: LineContext, Text: line[1:]})
+		case hunk != nil && line == "":
+			hunk.Lines = append(hunk.Lines, Line{Kind: LineContext, Text: ""})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	flushHunk()
+	if current != nil {
+		patches = append(patches, *current)
+	}
+
+	return patches, nil
+}
+
+// parseDiffGitHeader extracts the a/ and b/ paths from a "diff --git a/... b/..." line
+func parseDiffGitHeader(line string) (string, string, bool) {
+	rest := strings.TrimPrefix(line, diffGitPrefix)
+	idx := strings.Index(rest, " b/")
+	if !strings.HasPrefix(rest, "a/") || idx < 0 {
+		return "", "", false
+	}
+	a := strings.TrimPrefix(rest[:idx], "a/")
+	b := strings.TrimPrefix(rest[idx+1:], "b/")
+	return a, b, true
+}
+
+func trimDiffPath(path, prefix string) string {
+	if path == devNullPath {
+		return ""
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// parseHunkHeader parses a "@@ -l,s +l,s @@" header into a Hunk
+func parseHunkHeader(line string) (*Hunk, error) {
+	end := strings.Index(line[2:], "@@")
+	if end < 0 {
+		return nil, fmt.Errorf("malformed hunk header: %q", line)
+	}
+	ranges := strings.TrimSpace(line[2 : end+2])
+	parts := strings.Fields(ranges)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	oldStart, oldLines, err := parseHunkRange(parts[0], "-")
+	if err != nil {
+		return nil, err
+	}
+	newStart, newLines, err := parseHunkRange(parts[1], "+")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hunk{
+		OldStart: oldStart,
+		OldLines: oldLines,
+		NewStart: newStart,
+		NewLines: newLines,
+	}, nil
+}
+
+func parseHunkRange(s, sign string) (int, int, error) {
+	s = strings.TrimPrefix(s, sign)
+	start := s
+	lines := 1
+	if i := strings.Index(s, ","); i >= 0 {
+		start = s[:i]
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk range %q: %w", s, err)
+		}
+		lines = n
+	}
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk range %q: %w", s, err)
+	}
+	return n, lines, nil
+}
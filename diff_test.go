@@ -0,0 +1,94 @@
+package git
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPatch(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("line1\nline2\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("line1\nline2 changed\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+
+	log, err := c.Log()
+	assert.NoError(t, err)
+
+	patches, err := c.DiffPatch(log[1], log[0])
+	assert.NoError(t, err)
+	assert.Len(t, patches, 1)
+
+	p := patches[0]
+	assert.Equal(t, "readme.md", p.OldPath)
+	assert.Equal(t, "readme.md", p.NewPath)
+	assert.Len(t, p.Hunks, 1)
+
+	h := p.Hunks[0]
+	assert.Contains(t, h.Lines, Line{Kind: LineDelete, Text: "line2"})
+	assert.Contains(t, h.Lines, Line{Kind: LineAdd, Text: "line2 changed"})
+}
+
+func TestDiffFile(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "other.md"), []byte("#other"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey hey"), 0744))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "other.md"), []byte("#other other"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+
+	log, err := c.Log()
+	assert.NoError(t, err)
+
+	p, err := c.DiffFile(log[1], log[0], "readme.md")
+	assert.NoError(t, err)
+	assert.NotNil(t, p)
+	assert.Equal(t, "readme.md", p.NewPath)
+}
+
+func TestDiffPatchAddAndDelete(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("line1\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "newfile.md"), []byte("line1\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+
+	log, err := c.Log()
+	assert.NoError(t, err)
+
+	patches, err := c.DiffPatch(log[1], log[0])
+	assert.NoError(t, err)
+	assert.Len(t, patches, 1)
+	assert.Equal(t, "", patches[0].OldPath)
+	assert.Equal(t, "newfile.md", patches[0].NewPath)
+	assert.Equal(t, "100755", patches[0].NewMode)
+
+	patches, err = c.DiffPatch(log[0], log[1])
+	assert.NoError(t, err)
+	assert.Len(t, patches, 1)
+	assert.Equal(t, "newfile.md", patches[0].OldPath)
+	assert.Equal(t, "", patches[0].NewPath)
+	assert.Equal(t, "100755", patches[0].OldMode)
+}
@@ -0,0 +1,654 @@
+package git
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNotSupported is returned by DotGit methods that need a working tree,
+// the index, repository config, or a git binary, none of which this backend
+// uses.
+var ErrNotSupported = errors.New("not supported by the DotGit backend")
+
+// DotGit is a Repository backend that reads loose objects and packfiles
+// under path/.git directly, without invoking the git binary. It supports
+// only the object and ref reads of Repository: Status, Version, and the
+// methods that mutate the working tree, index, refs or config return
+// ErrNotSupported.
+type DotGit struct {
+	path   string
+	gitDir string
+	store  *objectStore
+}
+
+// NewDotGit opens the repository at path for direct object-database
+// access. path must contain a .git directory; gitdir files (as used by
+// worktrees and submodules) are not supported.
+func NewDotGit(path string) (*DotGit, error) {
+	gitDir := filepath.Join(path, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a git directory", gitDir)
+	}
+
+	store, err := newObjectStore(gitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DotGit{path: path, gitDir: gitDir, store: store}, nil
+}
+
+// Version is not supported: there is no git binary to report a version for.
+func (d *DotGit) Version() (string, error) {
+	return "", fmt.Errorf("Version: %w", ErrNotSupported)
+}
+
+// IndexAll is not supported: DotGit does not manage the index.
+func (d *DotGit) IndexAll() error {
+	return fmt.Errorf("IndexAll: %w", ErrNotSupported)
+}
+
+// Commit is not supported: DotGit does not manage the index or refs.
+func (d *DotGit) Commit(message string) error {
+	return fmt.Errorf("Commit: %w", ErrNotSupported)
+}
+
+// Status is not supported: it requires comparing the index against a
+// working tree, neither of which DotGit manages.
+func (d *DotGit) Status() ([]StatusEntry, error) {
+	return nil, fmt.Errorf("Status: %w", ErrNotSupported)
+}
+
+// ConfigureUser is not supported: DotGit does not write repository config.
+func (d *DotGit) ConfigureUser(username, email string) error {
+	return fmt.Errorf("ConfigureUser: %w", ErrNotSupported)
+}
+
+// CreateBranch is not supported: DotGit does not write refs or a working
+// tree.
+func (d *DotGit) CreateBranch(name string) error {
+	return fmt.Errorf("CreateBranch: %w", ErrNotSupported)
+}
+
+// Checkout is not supported: DotGit does not manage a working tree.
+func (d *DotGit) Checkout(path string) error {
+	return fmt.Errorf("Checkout: %w", ErrNotSupported)
+}
+
+// RevParse resolves a ref name (branch, tag, or HEAD) or a full object id
+// to the object id it points at, peeling annotated tags to their target.
+func (d *DotGit) RevParse(revisionOrPath string) (string, error) {
+	return d.resolveRev(revisionOrPath)
+}
+
+// MergeBase returns the most recent commit reachable from both first and
+// second by walking parent links.
+func (d *DotGit) MergeBase(first, second string) (string, error) {
+	firstSHA, err := d.resolveCommit(first)
+	if err != nil {
+		return "", err
+	}
+	secondSHA, err := d.resolveCommit(second)
+	if err != nil {
+		return "", err
+	}
+
+	ancestors := map[string]bool{}
+	queue := []string{firstSHA}
+	for len(queue) > 0 {
+		sha := queue[0]
+		queue = queue[1:]
+		if ancestors[sha] {
+			continue
+		}
+		ancestors[sha] = true
+		_, parents, _, err := d.readCommit(sha)
+		if err != nil {
+			return "", err
+		}
+		queue = append(queue, parents...)
+	}
+
+	queue = []string{secondSHA}
+	seen := map[string]bool{}
+	for len(queue) > 0 {
+		sha := queue[0]
+		queue = queue[1:]
+		if seen[sha] {
+			continue
+		}
+		seen[sha] = true
+		if ancestors[sha] {
+			return sha, nil
+		}
+		_, parents, _, err := d.readCommit(sha)
+		if err != nil {
+			return "", err
+		}
+		queue = append(queue, parents...)
+	}
+
+	return "", fmt.Errorf("no merge base between %s and %s", first, second)
+}
+
+// Log returns the ids of commits reachable from HEAD, most recent first,
+// matching `git log --pretty=%H`.
+func (d *DotGit) Log() ([]string, error) {
+	head, err := d.resolveRev("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	type visited struct {
+		sha string
+		ts  int64
+	}
+	var commits []visited
+	seen := map[string]bool{}
+	queue := []string{head}
+	for len(queue) > 0 {
+		sha := queue[0]
+		queue = queue[1:]
+		if seen[sha] {
+			continue
+		}
+		seen[sha] = true
+
+		_, parents, ts, err := d.readCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, visited{sha: sha, ts: ts})
+		queue = append(queue, parents...)
+	}
+
+	sort.SliceStable(commits, func(i, j int) bool { return commits[i].ts > commits[j].ts })
+
+	out := make([]string, len(commits))
+	for i, c := range commits {
+		out[i] = c.sha
+	}
+	return out, nil
+}
+
+// Diff returns the paths that differ between the trees of from and to,
+// matching `git diff-tree --no-commit-id -r --name-only`.
+func (d *DotGit) Diff(from, to string) ([]string, error) {
+	fromFiles, err := d.treeFiles(from)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := d.treeFiles(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for path, sha := range fromFiles {
+		if toFiles[path] != sha {
+			changed[path] = true
+		}
+	}
+	for path, sha := range toFiles {
+		if fromFiles[path] != sha {
+			changed[path] = true
+		}
+	}
+
+	paths := make([]string, 0, len(changed))
+	for path := range changed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Blob returns the content of path as recorded in the tree of sha.
+func (d *DotGit) Blob(sha, path string) (string, error) {
+	treeSHA, err := d.resolveTree(sha)
+	if err != nil {
+		return "", err
+	}
+	blobSHA, err := d.findBlob(treeSHA, path)
+	if err != nil {
+		return "", err
+	}
+	obj, err := d.store.readObject(blobSHA)
+	if err != nil {
+		return "", err
+	}
+	if obj.Type != objBlob {
+		return "", fmt.Errorf("%s:%s is not a blob", sha, path)
+	}
+	return string(obj.Data), nil
+}
+
+// LsTree returns every file path recorded in the tree of sha, matching
+// `git ls-tree --name-only -r`.
+func (d *DotGit) LsTree(sha string) ([]string, error) {
+	treeSHA, err := d.resolveTree(sha)
+	if err != nil {
+		return nil, err
+	}
+	files, err := d.walkTree(treeSHA, "")
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// treeFiles resolves rev to a tree and flattens it to a path->blob-sha map.
+func (d *DotGit) treeFiles(rev string) (map[string]string, error) {
+	treeSHA, err := d.resolveTree(rev)
+	if err != nil {
+		return nil, err
+	}
+	return d.walkTree(treeSHA, "")
+}
+
+// walkTree recursively flattens the tree at sha into path->blob-sha pairs,
+// with paths relative to prefix.
+func (d *DotGit) walkTree(sha, prefix string) (map[string]string, error) {
+	obj, err := d.store.readObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type != objTree {
+		return nil, fmt.Errorf("%s is not a tree", sha)
+	}
+
+	entries, err := parseTree(obj.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]string{}
+	for _, e := range entries {
+		entryPath := e.name
+		if prefix != "" {
+			entryPath = prefix + "/" + e.name
+		}
+		if e.mode == "40000" {
+			sub, err := d.walkTree(e.sha, entryPath)
+			if err != nil {
+				return nil, err
+			}
+			for p, s := range sub {
+				out[p] = s
+			}
+			continue
+		}
+		out[entryPath] = e.sha
+	}
+	return out, nil
+}
+
+// findBlob walks path's components down from the tree at treeSHA and
+// returns the blob id at the end of it.
+func (d *DotGit) findBlob(treeSHA, path string) (string, error) {
+	sha := treeSHA
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		obj, err := d.store.readObject(sha)
+		if err != nil {
+			return "", err
+		}
+		if obj.Type != objTree {
+			return "", fmt.Errorf("%s is not a tree", sha)
+		}
+		entries, err := parseTree(obj.Data)
+		if err != nil {
+			return "", err
+		}
+
+		found := false
+		for _, e := range entries {
+			if e.name != part {
+				continue
+			}
+			sha = e.sha
+			found = true
+			break
+		}
+		if !found {
+			return "", fmt.Errorf("path not found: %s", path)
+		}
+		if i == len(parts)-1 {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("path not found: %s", path)
+}
+
+// resolveTree resolves rev, which may name a commit, a tag, or a tree
+// directly, to the id of the tree it describes. This is the hot path for
+// Blob/LsTree/Diff, so it consults the commit-graph file before falling
+// back to decoding the commit object directly.
+func (d *DotGit) resolveTree(rev string) (string, error) {
+	sha, err := d.resolveRev(rev)
+	if err != nil {
+		return "", err
+	}
+
+	if tree, _, _, ok := d.store.commitParents(sha); ok {
+		return tree, nil
+	}
+
+	obj, err := d.store.readObject(sha)
+	if err != nil {
+		return "", err
+	}
+	switch obj.Type {
+	case objTree:
+		return sha, nil
+	case objCommit:
+		tree, _, _, err := parseCommit(obj.Data)
+		if err != nil {
+			return "", err
+		}
+		return tree, nil
+	default:
+		return "", fmt.Errorf("%s is neither a commit nor a tree", rev)
+	}
+}
+
+// resolveCommit resolves rev to a commit id, peeling tags along the way.
+func (d *DotGit) resolveCommit(rev string) (string, error) {
+	sha, err := d.resolveRev(rev)
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := d.store.readObject(sha)
+	if err != nil {
+		return "", err
+	}
+	if obj.Type != objCommit {
+		return "", fmt.Errorf("%s is not a commit", rev)
+	}
+	return sha, nil
+}
+
+// readCommit returns the tree id, parent ids, and committer timestamp for
+// the commit at sha, preferring the commit-graph file when the repository
+// has one and falling back to decoding the commit object directly.
+func (d *DotGit) readCommit(sha string) (tree string, parents []string, committerTime int64, err error) {
+	if tree, parents, committerTime, ok := d.store.commitParents(sha); ok {
+		return tree, parents, committerTime, nil
+	}
+
+	obj, err := d.store.readObject(sha)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	if obj.Type != objCommit {
+		return "", nil, 0, fmt.Errorf("%s is not a commit", sha)
+	}
+	return parseCommit(obj.Data)
+}
+
+// resolveRev resolves a ref name or object id to a full 40-character hex
+// object id, peeling annotated tags to the object they point at.
+func (d *DotGit) resolveRev(rev string) (string, error) {
+	sha, err := d.resolveRef(rev)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		obj, err := d.store.readObject(sha)
+		if err != nil {
+			return "", err
+		}
+		if obj.Type != objTag {
+			return sha, nil
+		}
+		_, target, err := parseTag(obj.Data)
+		if err != nil {
+			return "", err
+		}
+		sha = target
+	}
+}
+
+// resolveRef resolves a ref name or object id to the object id it names,
+// without peeling tags. It understands HEAD, short branch/tag names,
+// fully-qualified refs, and loose object ids, checking loose refs first and
+// falling back to packed-refs.
+func (d *DotGit) resolveRef(rev string) (string, error) {
+	if looksLikeSHA(rev) {
+		return rev, nil
+	}
+
+	candidates := []string{rev}
+	if rev == "HEAD" {
+		return d.resolveSymbolicRef("HEAD", 0)
+	}
+	candidates = append(candidates,
+		"refs/"+rev,
+		"refs/heads/"+rev,
+		"refs/tags/"+rev,
+		"refs/remotes/"+rev,
+	)
+
+	for _, ref := range candidates {
+		if sha, err := d.readLooseRef(ref); err == nil {
+			return sha, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	packed, err := d.readPackedRefs()
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range candidates {
+		if sha, ok := packed[ref]; ok {
+			return sha, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown revision: %s", rev)
+}
+
+// resolveSymbolicRef follows HEAD (or another symbolic ref) through its
+// "ref: <target>" indirection to a commit id, bounding recursion to guard
+// against a ref cycle.
+func (d *DotGit) resolveSymbolicRef(ref string, depth int) (string, error) {
+	if depth > 10 {
+		return "", fmt.Errorf("too many levels of symbolic ref indirection resolving %s", ref)
+	}
+
+	raw, err := ioutil.ReadFile(filepath.Join(d.gitDir, ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			packed, perr := d.readPackedRefs()
+			if perr != nil {
+				return "", perr
+			}
+			if sha, ok := packed[ref]; ok {
+				return sha, nil
+			}
+		}
+		return "", err
+	}
+
+	content := strings.TrimSpace(string(raw))
+	if strings.HasPrefix(content, "ref: ") {
+		return d.resolveSymbolicRef(strings.TrimPrefix(content, "ref: "), depth+1)
+	}
+	if looksLikeSHA(content) {
+		return content, nil
+	}
+	return "", fmt.Errorf("malformed ref %s: %q", ref, content)
+}
+
+// readLooseRef reads a single ref file (e.g. refs/heads/master) relative
+// to the git directory.
+func (d *DotGit) readLooseRef(ref string) (string, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(d.gitDir, ref))
+	if err != nil {
+		return "", err
+	}
+	sha := strings.TrimSpace(string(raw))
+	if !looksLikeSHA(sha) {
+		return "", fmt.Errorf("malformed ref %s: %q", ref, sha)
+	}
+	return sha, nil
+}
+
+// readPackedRefs parses .git/packed-refs, which holds refs that have been
+// compacted out of the loose refs/ tree.
+func (d *DotGit) readPackedRefs() (map[string]string, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(d.gitDir, "packed-refs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	refs := map[string]string{}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		refs[fields[1]] = fields[0]
+	}
+	return refs, nil
+}
+
+// looksLikeSHA reports whether s is a full 40-character hex object id.
+// Abbreviated ids are not supported.
+func looksLikeSHA(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// treeEntry is one row of a parsed tree object.
+type treeEntry struct {
+	mode string
+	name string
+	sha  string
+}
+
+// parseTree decodes a tree object's body: a sequence of
+// "<mode> <name>\0<20-byte sha>" entries with no separator between entries.
+func parseTree(data []byte) ([]treeEntry, error) {
+	var entries []treeEntry
+	for len(data) > 0 {
+		sp := indexByte(data, ' ')
+		if sp < 0 {
+			return nil, errors.New("malformed tree entry: missing mode separator")
+		}
+		mode := string(data[:sp])
+		data = data[sp+1:]
+
+		nul := indexByte(data, 0)
+		if nul < 0 {
+			return nil, errors.New("malformed tree entry: missing name terminator")
+		}
+		name := string(data[:nul])
+		data = data[nul+1:]
+
+		if len(data) < 20 {
+			return nil, errors.New("malformed tree entry: truncated object id")
+		}
+		sha := hex.EncodeToString(data[:20])
+		data = data[20:]
+
+		entries = append(entries, treeEntry{mode: mode, name: name, sha: sha})
+	}
+	return entries, nil
+}
+
+func indexByte(data []byte, b byte) int {
+	for i, c := range data {
+		if c == b {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseCommit decodes a commit object's text body, returning its tree id,
+// parent ids, and committer timestamp (seconds since epoch).
+func parseCommit(data []byte) (tree string, parents []string, committerTime int64, err error) {
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		if line == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "tree "):
+			tree = strings.TrimPrefix(line, "tree ")
+		case strings.HasPrefix(line, "parent "):
+			parents = append(parents, strings.TrimPrefix(line, "parent "))
+		case strings.HasPrefix(line, "committer "):
+			committerTime = parseCommitterTimestamp(strings.TrimPrefix(line, "committer "))
+		}
+	}
+	if tree == "" {
+		return "", nil, 0, errors.New("malformed commit: missing tree")
+	}
+	return tree, parents, committerTime, nil
+}
+
+// parseCommitterTimestamp extracts the unix timestamp from a committer
+// line's trailing "<name> <email> <timestamp> <tz>".
+func parseCommitterTimestamp(field string) int64 {
+	fields := strings.Fields(field)
+	if len(fields) < 2 {
+		return 0
+	}
+	ts, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return ts
+}
+
+// parseTag decodes an annotated tag object's text body, returning the type
+// of object it points at and the id it points at.
+func parseTag(data []byte) (tagType string, target string, err error) {
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		if line == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "object "):
+			target = strings.TrimPrefix(line, "object ")
+		case strings.HasPrefix(line, "type "):
+			tagType = strings.TrimPrefix(line, "type ")
+		}
+	}
+	if target == "" {
+		return "", "", errors.New("malformed tag: missing object")
+	}
+	return tagType, target, nil
+}
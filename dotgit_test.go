@@ -0,0 +1,202 @@
+package git
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runGit runs a git command directly against DataPath, for test setup
+// steps (packing, writing a commit-graph) that CLI has no method for.
+func runGit(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = DataPath
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestDotGitLog(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "other.md"), []byte("#other"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+
+	wantLog, err := c.Log()
+	assert.NoError(t, err)
+
+	dg, err := NewDotGit(DataPath)
+	assert.NoError(t, err)
+
+	gotLog, err := dg.Log()
+	assert.NoError(t, err)
+	assert.Equal(t, wantLog, gotLog)
+}
+
+func TestDotGitBlobAndLsTree(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	head, err := c.RevParse("HEAD")
+	assert.NoError(t, err)
+
+	dg, err := NewRepository(DataPath, WithDotGit())
+	assert.NoError(t, err)
+
+	files, err := dg.LsTree(head)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"readme.md"}, files)
+
+	blob, err := dg.Blob(head, "readme.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "#hey", blob)
+}
+
+func TestDotGitDiffAndMergeBase(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+	first, err := c.RevParse("HEAD")
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "other.md"), []byte("#other"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+	second, err := c.RevParse("HEAD")
+	assert.NoError(t, err)
+
+	dg, err := NewDotGit(DataPath)
+	assert.NoError(t, err)
+
+	changed, err := dg.Diff(first, second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other.md"}, changed)
+
+	base, err := dg.MergeBase(first, second)
+	assert.NoError(t, err)
+	assert.Equal(t, first, base)
+}
+
+func TestDotGitUnsupportedOperations(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	dg, err := NewDotGit(DataPath)
+	assert.NoError(t, err)
+
+	_, err = dg.Status()
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	err = dg.IndexAll()
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	err = dg.Commit("third")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+// TestDotGitPackedRepository exercises reads against objects that only
+// exist in a packfile written by a real `git gc`, including any ofs-delta
+// chains it builds, rather than the loose objects every other test reads.
+func TestDotGitPackedRepository(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+
+	var wantLog []string
+	content := "line1\n"
+	for i := 0; i < 5; i++ {
+		content += "line2\n"
+		assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte(content), 0744))
+		assert.NoError(t, c.IndexAll())
+		assert.NoError(t, c.Commit("commit"))
+	}
+	wantLog, err := c.Log()
+	assert.NoError(t, err)
+
+	runGit(t, "gc")
+
+	dg, err := NewDotGit(DataPath)
+	assert.NoError(t, err)
+
+	gotLog, err := dg.Log()
+	assert.NoError(t, err)
+	assert.Equal(t, wantLog, gotLog)
+
+	blob, err := dg.Blob(wantLog[0], "readme.md")
+	assert.NoError(t, err)
+	assert.Equal(t, content, blob)
+}
+
+// TestDotGitCommitGraphAcceleration exercises the commit-graph fast path
+// for parent/tree lookups, including a two-parent merge commit whose
+// parents are both recorded directly in the Commit Data chunk.
+func TestDotGitCommitGraphAcceleration(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("base"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("base"))
+
+	runGit(t, "checkout", "-q", "-b", "feature")
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "feature.md"), []byte("feature"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("feature"))
+
+	runGit(t, "checkout", "-q", "master")
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "main.md"), []byte("main"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("main"))
+	runGit(t, "merge", "--no-ff", "-q", "-m", "merge", "feature")
+
+	wantLog, err := c.Log()
+	assert.NoError(t, err)
+
+	runGit(t, "commit-graph", "write", "--reachable")
+
+	dg, err := NewDotGit(DataPath)
+	assert.NoError(t, err)
+	assert.NotNil(t, dg.store.graph)
+
+	gotLog, err := dg.Log()
+	assert.NoError(t, err)
+	assert.Equal(t, wantLog, gotLog)
+
+	mergeBase, err := dg.MergeBase(wantLog[0], wantLog[len(wantLog)-1])
+	assert.NoError(t, err)
+	assert.Equal(t, wantLog[len(wantLog)-1], mergeBase)
+
+	files, err := dg.LsTree(wantLog[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"feature.md", "main.md", "readme.md"}, files)
+
+	blob, err := dg.Blob(wantLog[0], "readme.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "base", blob)
+}
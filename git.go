@@ -3,19 +3,13 @@ package git
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
-	"fmt"
+	"io"
 	"os/exec"
-	"regexp"
 	"strings"
 )
 
-var statusStringRegexp *regexp.Regexp
-
-func init() {
-	statusStringRegexp = regexp.MustCompile(`^(\?\?|A|M|D|R)\s+(.*)$`)
-}
-
 // Status represents the kind of change made to tracked file
 type Status string
 
@@ -74,28 +68,6 @@ func (c CLI) Init() error {
 	return err
 }
 
-// Status runs git status command
-func (c CLI) Status() ([]StatusEntry, error) {
-	l, err := c.runCommand("status", "-s")
-	if err != nil {
-		return nil, err
-	}
-
-	r := make([]StatusEntry, len(l))
-	for i, e := range l {
-		matches := statusStringRegexp.FindStringSubmatch(e)
-		if matches == nil {
-			return nil, errors.New("Unable to parse status string")
-		}
-		r[i] = StatusEntry{
-			Status: statusTable[matches[1]],
-			Path:   matches[2],
-		}
-	}
-
-	return r, nil
-}
-
 // IndexAll stages all changes in workspace
 func (c CLI) IndexAll() error {
 	_, err := c.runCommand("add", "-A")
@@ -159,14 +131,39 @@ func (c CLI) Diff(from, to string) ([]string, error) {
 	return c.runCommand("diff-tree", "--no-commit-id", "-r", "--name-only", from, to)
 }
 
-// Blob returns the output of show <sha>:path
+// Blob returns the output of show <sha>:path. Internally it reads the blob
+// through BlobCtx's stream and returns the whole content as a string.
 func (c CLI) Blob(sha, path string) (string, error) {
-	return c.runCommandAndReadOutputAsString("show", fmt.Sprintf("%s:%s", sha, path))
+	stream, err := c.BlobCtx(context.Background(), sha, path)
+	if err != nil {
+		return "", err
+	}
+	buf, readErr := io.ReadAll(stream)
+	if closeErr := stream.Close(); closeErr != nil {
+		return "", closeErr
+	}
+	if readErr != nil {
+		return "", readErr
+	}
+	return string(buf), nil
 }
 
-// LsTree returns the output of ls-tree -r --name-only <sha>
+// LsTree returns the output of ls-tree -r --name-only <sha>. Internally it
+// streams the listing through LsTreeCtx rather than buffering git's entire
+// output before reading any of it.
 func (c CLI) LsTree(sha string) ([]string, error) {
-	return c.runCommand("ls-tree", "--name-only", "-r", sha)
+	stream, err := c.LsTreeCtx(context.Background(), sha)
+	if err != nil {
+		return nil, err
+	}
+	buf, readErr := io.ReadAll(stream)
+	if closeErr := stream.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return readLines(buf)
 }
 
 // runCommand implements the driver for running git with specified arguments
@@ -0,0 +1,336 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileChange is a single file touched by a Commit, as reported by
+// `git log --name-status`.
+type FileChange struct {
+	// Status is the raw status code git reports, e.g. "A", "M", "D", or
+	// "R100"/"C100" for renames/copies above the similarity threshold.
+	Status string
+	// Path is the file's path. For renames/copies this is the new path.
+	Path string
+	// OldPath is only set for renames/copies, and is the path the file
+	// was renamed/copied from.
+	OldPath string
+}
+
+// Commit is a single commit as walked by CommitGraph.
+type Commit struct {
+	SHA            string
+	Parents        []string
+	AuthorName     string
+	AuthorEmail    string
+	AuthorDate     time.Time
+	CommitterName  string
+	CommitterEmail string
+	CommitDate     time.Time
+	Subject        string
+	Body           string
+	Changes        []FileChange
+}
+
+const (
+	recordStart byte = 0x01
+	fieldSep    byte = 0x02
+	headerEnd   byte = 0x03
+)
+
+var logFormat = fmt.Sprintf(
+	"%c%%H%c%%P%c%%an%c%%ae%c%%at%c%%cn%c%%ce%c%%ct%c%%s%c%%b%c",
+	recordStart, fieldSep, fieldSep, fieldSep, fieldSep, fieldSep, fieldSep, fieldSep, fieldSep, fieldSep, headerEnd,
+)
+
+// CommitGraphQuery builds a filtered, lazily-walked view of the commit
+// graph. Construct one with CLI.CommitGraph.
+type CommitGraphQuery struct {
+	cli        CLI
+	rev        string
+	excludeRev string
+	path       string
+	authorExpr string
+	committer  string
+	since      time.Time
+	until      time.Time
+	limit      int
+}
+
+// CommitGraph starts a query over the commit graph reachable from HEAD.
+func (c CLI) CommitGraph() *CommitGraphQuery {
+	return &CommitGraphQuery{cli: c, rev: "HEAD"}
+}
+
+// From walks the graph starting at rev instead of HEAD.
+func (q *CommitGraphQuery) From(rev string) *CommitGraphQuery {
+	q.rev = rev
+	return q
+}
+
+// Not excludes commits reachable from rev, giving "commits reachable from
+// From() but not from rev" semantics (git's `rev..From()` range).
+func (q *CommitGraphQuery) Not(rev string) *CommitGraphQuery {
+	q.excludeRev = rev
+	return q
+}
+
+// WithPath restricts the walk to commits that touched path, following
+// renames across the history of that path.
+func (q *CommitGraphQuery) WithPath(path string) *CommitGraphQuery {
+	q.path = path
+	return q
+}
+
+// WithAuthor restricts the walk to commits whose author matches the given
+// regular expression.
+func (q *CommitGraphQuery) WithAuthor(pattern string) *CommitGraphQuery {
+	q.authorExpr = pattern
+	return q
+}
+
+// WithCommitter restricts the walk to commits whose committer matches the
+// given regular expression.
+func (q *CommitGraphQuery) WithCommitter(pattern string) *CommitGraphQuery {
+	q.committer = pattern
+	return q
+}
+
+// Since restricts the walk to commits whose commit date is on or after t.
+func (q *CommitGraphQuery) Since(t time.Time) *CommitGraphQuery {
+	q.since = t
+	return q
+}
+
+// Until restricts the walk to commits whose commit date is on or before t.
+func (q *CommitGraphQuery) Until(t time.Time) *CommitGraphQuery {
+	q.until = t
+	return q
+}
+
+// Limit caps the number of commits the walk will return.
+func (q *CommitGraphQuery) Limit(n int) *CommitGraphQuery {
+	q.limit = n
+	return q
+}
+
+// Iter starts the walk and returns a lazy CommitIterator: commits are parsed
+// from the underlying `git log` process as they are requested, rather than
+// all being buffered up front.
+func (q *CommitGraphQuery) Iter() (*CommitIterator, error) {
+	args := []string{"log", "--format=" + logFormat, "--name-status", "-z"}
+	if q.path != "" {
+		args = append(args, "--follow")
+	}
+	if q.authorExpr != "" {
+		args = append(args, "--author="+q.authorExpr)
+	}
+	if q.committer != "" {
+		args = append(args, "--committer="+q.committer)
+	}
+	if !q.since.IsZero() {
+		args = append(args, "--since="+q.since.Format(time.RFC3339))
+	}
+	if !q.until.IsZero() {
+		args = append(args, "--until="+q.until.Format(time.RFC3339))
+	}
+	if q.limit > 0 {
+		args = append(args, fmt.Sprintf("-%d", q.limit))
+	}
+
+	rev := q.rev
+	if q.excludeRev != "" {
+		rev = fmt.Sprintf("%s..%s", q.excludeRev, q.rev)
+	}
+	args = append(args, rev)
+
+	if q.path != "" {
+		args = append(args, "--", q.path)
+	}
+
+	stream, err := q.cli.RunStream(context.Background(), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	scanner.Split(splitCommitRecords)
+
+	return &CommitIterator{stream: stream, scanner: scanner}, nil
+}
+
+// CommitIterator lazily yields commits from a CommitGraphQuery.
+type CommitIterator struct {
+	stream interface {
+		Close() error
+	}
+	scanner *bufio.Scanner
+}
+
+// Next returns the next commit in the walk. ok is false once the walk is
+// exhausted, in which case err is nil.
+func (it *CommitIterator) Next() (commit *Commit, ok bool, err error) {
+	for it.scanner.Scan() {
+		token := it.scanner.Bytes()
+		if len(token) == 0 {
+			continue
+		}
+		c, err := parseCommitRecord(token)
+		if err != nil {
+			return nil, false, err
+		}
+		return c, true, nil
+	}
+	if err := it.scanner.Err(); err != nil {
+		return nil, false, err
+	}
+	return nil, false, nil
+}
+
+// Close releases the underlying `git log` process. It is safe to call Close
+// before the iterator is exhausted to stop walking early.
+func (it *CommitIterator) Close() error {
+	return it.stream.Close()
+}
+
+// LatestCommitForPath returns the most recent commit reachable from rev
+// that touched path, following renames. It is a fast path for UIs that want
+// "last commit that touched each entry" without walking the whole history
+// or shelling out once per path.
+func (c CLI) LatestCommitForPath(rev, path string) (*Commit, error) {
+	it, err := c.CommitGraph().From(rev).WithPath(path).Limit(1).Iter()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	commit, ok, err := it.Next()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	return commit, nil
+}
+
+// splitCommitRecords is a bufio.SplitFunc that splits a stream framed by
+// recordStart bytes (as produced by logFormat) into one token per commit,
+// each containing that commit's header fields and its --name-status -z
+// output.
+func splitCommitRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	offset := 0
+	if len(data) > 0 && data[0] == recordStart {
+		offset = 1
+	}
+
+	if idx := bytes.IndexByte(data[offset:], recordStart); idx >= 0 {
+		return offset + idx, data[offset : offset+idx], nil
+	}
+
+	if atEOF {
+		return len(data), data[offset:], nil
+	}
+
+	return 0, nil, nil
+}
+
+func parseCommitRecord(token []byte) (*Commit, error) {
+	headerEndIdx := bytes.IndexByte(token, headerEnd)
+	if headerEndIdx < 0 {
+		return nil, fmt.Errorf("malformed commit record: missing header terminator")
+	}
+	header := string(token[:headerEndIdx])
+	rest := token[headerEndIdx+1:]
+
+	fields := strings.SplitN(header, string(fieldSep), 10)
+	if len(fields) != 10 {
+		return nil, fmt.Errorf("malformed commit header: %q", header)
+	}
+
+	authorTime, err := parseUnixTimestamp(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	commitTime, err := parseUnixTimestamp(fields[7])
+	if err != nil {
+		return nil, err
+	}
+
+	var parents []string
+	if fields[1] != "" {
+		parents = strings.Fields(fields[1])
+	}
+
+	commit := &Commit{
+		SHA:            fields[0],
+		Parents:        parents,
+		AuthorName:     fields[2],
+		AuthorEmail:    fields[3],
+		AuthorDate:     authorTime,
+		CommitterName:  fields[5],
+		CommitterEmail: fields[6],
+		CommitDate:     commitTime,
+		Subject:        fields[8],
+		Body:           fields[9],
+	}
+
+	commit.Changes = parseNameStatus(rest)
+	return commit, nil
+}
+
+// parseNameStatus parses the `--name-status -z` output trailing a commit's
+// header: a run of NUL-terminated tokens, each starting with a status code
+// followed by one path (two for renames/copies).
+func parseNameStatus(rest []byte) []FileChange {
+	rest = bytes.TrimPrefix(rest, []byte("\n"))
+	parts := bytes.Split(rest, []byte{0})
+
+	var changes []FileChange
+	for i := 0; i < len(parts); i++ {
+		status := string(parts[i])
+		if status == "" {
+			continue
+		}
+		if strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C") {
+			if i+2 >= len(parts) {
+				break
+			}
+			changes = append(changes, FileChange{
+				Status:  status,
+				OldPath: string(parts[i+1]),
+				Path:    string(parts[i+2]),
+			})
+			i += 2
+			continue
+		}
+		if i+1 >= len(parts) {
+			break
+		}
+		changes = append(changes, FileChange{
+			Status: status,
+			Path:   string(parts[i+1]),
+		})
+		i++
+	}
+	return changes
+}
+
+func parseUnixTimestamp(s string) (time.Time, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed timestamp %q: %w", s, err)
+	}
+	return time.Unix(n, 0), nil
+}
@@ -0,0 +1,86 @@
+package git
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitGraphWalk(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "other.md"), []byte("#other"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+
+	it, err := c.CommitGraph().Iter()
+	assert.NoError(t, err)
+	defer it.Close()
+
+	var subjects []string
+	for {
+		commit, ok, err := it.Next()
+		assert.NoError(t, err)
+		if !ok {
+			break
+		}
+		subjects = append(subjects, commit.Subject)
+	}
+	assert.Equal(t, []string{"second", "first"}, subjects)
+}
+
+func TestCommitGraphWithPath(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "other.md"), []byte("#other"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+
+	it, err := c.CommitGraph().WithPath("readme.md").Iter()
+	assert.NoError(t, err)
+	defer it.Close()
+
+	commit, ok, err := it.Next()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "first", commit.Subject)
+	assert.Len(t, commit.Changes, 1)
+	assert.Equal(t, "readme.md", commit.Changes[0].Path)
+
+	_, ok, err = it.Next()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLatestCommitForPath(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "other.md"), []byte("#other"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("second"))
+
+	commit, err := c.LatestCommitForPath("HEAD", "readme.md")
+	assert.NoError(t, err)
+	assert.NotNil(t, commit)
+	assert.Equal(t, "first", commit.Subject)
+}
@@ -0,0 +1,524 @@
+package git
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// objectType identifies the four object kinds git stores, using the same
+// numbering as the pack format so pack object headers can be used directly.
+type objectType int
+
+const (
+	objCommit   objectType = 1
+	objTree     objectType = 2
+	objBlob     objectType = 3
+	objTag      objectType = 4
+	objOfsDelta objectType = 6
+	objRefDelta objectType = 7
+)
+
+func (t objectType) String() string {
+	switch t {
+	case objCommit:
+		return "commit"
+	case objTree:
+		return "tree"
+	case objBlob:
+		return "blob"
+	case objTag:
+		return "tag"
+	default:
+		return "unknown"
+	}
+}
+
+// object is a single decoded git object: its type and its uncompressed
+// content, with loose-object headers and delta chains already resolved.
+type object struct {
+	Type objectType
+	Data []byte
+}
+
+// objectStore reads loose objects and packfiles directly from a .git
+// directory without invoking the git binary.
+type objectStore struct {
+	gitDir string
+	packs  []*packIndex
+	graph  *commitGraph
+}
+
+// newObjectStore opens the object database rooted at gitDir, eagerly
+// parsing every packfile's .idx so lookups can binary-search the fanout
+// table instead of scanning pack data, and the commit-graph file if the
+// repository has one.
+func newObjectStore(gitDir string) (*objectStore, error) {
+	s := &objectStore{gitDir: gitDir}
+
+	graph, err := loadCommitGraph(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	s.graph = graph
+
+	packDir := filepath.Join(gitDir, "objects", "pack")
+	entries, err := ioutil.ReadDir(packDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) != ".idx" {
+			continue
+		}
+		packPath := filepath.Join(packDir, e.Name()[:len(e.Name())-len(".idx")]+".pack")
+		pi, err := parsePackIndex(filepath.Join(packDir, e.Name()), packPath)
+		if err != nil {
+			return nil, err
+		}
+		s.packs = append(s.packs, pi)
+	}
+
+	return s, nil
+}
+
+// commitParents returns the tree id, parent ids, and committer timestamp
+// for sha using the commit-graph file, if the repository has one and it
+// covers sha. ok is false if there is no usable commit-graph entry, in
+// which case the caller should fall back to decoding the commit object.
+func (s *objectStore) commitParents(sha string) (tree string, parents []string, committerTime int64, ok bool) {
+	if s.graph == nil {
+		return "", nil, 0, false
+	}
+	return s.graph.parents(sha)
+}
+
+// readObject returns the decoded object for sha, resolving delta chains
+// and trying loose objects before packs.
+func (s *objectStore) readObject(sha string) (*object, error) {
+	return s.readObjectBytes(sha)
+}
+
+func (s *objectStore) readObjectBytes(sha string) (*object, error) {
+	if obj, err := s.readLooseObject(sha); err == nil {
+		return obj, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	shaBytes, err := hex.DecodeString(sha)
+	if err != nil || len(shaBytes) != 20 {
+		return nil, fmt.Errorf("malformed object id %q", sha)
+	}
+
+	for _, pi := range s.packs {
+		if offset, ok := pi.find(shaBytes); ok {
+			return s.readPackObjectAt(pi, offset)
+		}
+	}
+
+	return nil, fmt.Errorf("object not found: %s", sha)
+}
+
+// readLooseObject reads objects/<xx>/<rest>, which is a zlib-compressed
+// "<type> <size>\0<content>" stream.
+func (s *objectStore) readLooseObject(sha string) (*object, error) {
+	if len(sha) < 3 {
+		return nil, fmt.Errorf("malformed object id %q", sha)
+	}
+	path := filepath.Join(s.gitDir, "objects", sha[:2], sha[2:])
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	raw, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	nul := bytes.IndexByte(raw, 0)
+	if nul < 0 {
+		return nil, errors.New("malformed loose object: missing header terminator")
+	}
+	header := string(raw[:nul])
+	var typeName string
+	var size int
+	if _, err := fmt.Sscanf(header, "%s %d", &typeName, &size); err != nil {
+		return nil, fmt.Errorf("malformed loose object header %q: %w", header, err)
+	}
+
+	t, err := parseObjectTypeName(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &object{Type: t, Data: raw[nul+1:]}, nil
+}
+
+func parseObjectTypeName(name string) (objectType, error) {
+	switch name {
+	case "commit":
+		return objCommit, nil
+	case "tree":
+		return objTree, nil
+	case "blob":
+		return objBlob, nil
+	case "tag":
+		return objTag, nil
+	default:
+		return 0, fmt.Errorf("unknown object type %q", name)
+	}
+}
+
+// readPackObjectAt decodes the object stored at offset in pi's packfile,
+// recursively resolving ofs-delta and ref-delta chains against their base
+// objects.
+func (s *objectStore) readPackObjectAt(pi *packIndex, offset uint64) (*object, error) {
+	f, err := os.Open(pi.packPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return s.readPackObjectAtReader(f, pi, offset)
+}
+
+func (s *objectStore) readPackObjectAtReader(f *os.File, pi *packIndex, offset uint64) (*object, error) {
+	if _, err := f.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	t, size, headerLen, err := readPackObjectHeader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case objOfsDelta:
+		negOffset, deltaHeaderLen, err := readOffsetDeltaBase(f)
+		if err != nil {
+			return nil, err
+		}
+		baseOffset := offset - negOffset
+		delta, err := inflatePackData(f, size)
+		if err != nil {
+			return nil, err
+		}
+		_ = deltaHeaderLen
+		base, err := s.readPackObjectAtReader(f, pi, baseOffset)
+		if err != nil {
+			return nil, err
+		}
+		data, err := applyDelta(base.Data, delta)
+		if err != nil {
+			return nil, err
+		}
+		return &object{Type: base.Type, Data: data}, nil
+
+	case objRefDelta:
+		baseSHA := make([]byte, 20)
+		if _, err := io.ReadFull(f, baseSHA); err != nil {
+			return nil, err
+		}
+		delta, err := inflatePackData(f, size)
+		if err != nil {
+			return nil, err
+		}
+		base, err := s.readObjectBytes(hex.EncodeToString(baseSHA))
+		if err != nil {
+			return nil, err
+		}
+		data, err := applyDelta(base.Data, delta)
+		if err != nil {
+			return nil, err
+		}
+		return &object{Type: base.Type, Data: data}, nil
+
+	default:
+		_ = headerLen
+		data, err := inflatePackData(f, size)
+		if err != nil {
+			return nil, err
+		}
+		return &object{Type: t, Data: data}, nil
+	}
+}
+
+// readPackObjectHeader reads the variable-length type+size header at the
+// current position of f, matching the pack format: the first byte packs a
+// continuation bit, a 3-bit type, and 4 low size bits; each continuation
+// byte contributes 7 more size bits.
+func readPackObjectHeader(f *os.File) (objectType, uint64, int, error) {
+	var buf [1]byte
+	if _, err := io.ReadFull(f, buf[:]); err != nil {
+		return 0, 0, 0, err
+	}
+	n := 1
+	b := buf[0]
+	t := objectType((b >> 4) & 0x7)
+	size := uint64(b & 0x0f)
+	shift := uint(4)
+	for b&0x80 != 0 {
+		if _, err := io.ReadFull(f, buf[:]); err != nil {
+			return 0, 0, 0, err
+		}
+		n++
+		b = buf[0]
+		size |= uint64(b&0x7f) << shift
+		shift += 7
+	}
+	return t, size, n, nil
+}
+
+// readOffsetDeltaBase reads the varint-encoded negative offset that follows
+// an ofs-delta object's type+size header, using git's big-endian-ish base128
+// encoding where each continuation adds one to account for the value having
+// already been represented by the shorter encoding.
+func readOffsetDeltaBase(f *os.File) (uint64, int, error) {
+	var buf [1]byte
+	if _, err := io.ReadFull(f, buf[:]); err != nil {
+		return 0, 0, err
+	}
+	n := 1
+	b := buf[0]
+	value := uint64(b & 0x7f)
+	for b&0x80 != 0 {
+		if _, err := io.ReadFull(f, buf[:]); err != nil {
+			return 0, 0, err
+		}
+		n++
+		b = buf[0]
+		value = ((value + 1) << 7) | uint64(b&0x7f)
+	}
+	return value, n, nil
+}
+
+// inflatePackData zlib-decompresses exactly one object's worth of data
+// (expectedSize bytes) starting at the reader's current position.
+func inflatePackData(f *os.File, expectedSize uint64) ([]byte, error) {
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(zr, int64(expectedSize)))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// applyDelta reconstructs a target object from a base object's bytes and a
+// git delta stream: a source-size varint, a target-size varint, then a
+// sequence of copy (from source) and insert (literal) instructions.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	srcSize, delta, err := readDeltaVarint(delta)
+	if err != nil {
+		return nil, err
+	}
+	if int(srcSize) != len(base) {
+		return nil, fmt.Errorf("delta base size mismatch: expected %d, got %d", srcSize, len(base))
+	}
+	targetSize, delta, err := readDeltaVarint(delta)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, targetSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+
+		if op&0x80 != 0 {
+			var offset, size uint64
+			if op&0x01 != 0 {
+				offset |= uint64(delta[0])
+				delta = delta[1:]
+			}
+			if op&0x02 != 0 {
+				offset |= uint64(delta[0]) << 8
+				delta = delta[1:]
+			}
+			if op&0x04 != 0 {
+				offset |= uint64(delta[0]) << 16
+				delta = delta[1:]
+			}
+			if op&0x08 != 0 {
+				offset |= uint64(delta[0]) << 24
+				delta = delta[1:]
+			}
+			if op&0x10 != 0 {
+				size |= uint64(delta[0])
+				delta = delta[1:]
+			}
+			if op&0x20 != 0 {
+				size |= uint64(delta[0]) << 8
+				delta = delta[1:]
+			}
+			if op&0x40 != 0 {
+				size |= uint64(delta[0]) << 16
+				delta = delta[1:]
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > uint64(len(base)) {
+				return nil, errors.New("delta copy instruction out of range")
+			}
+			out = append(out, base[offset:offset+size]...)
+		} else if op != 0 {
+			n := int(op)
+			if n > len(delta) {
+				return nil, errors.New("delta insert instruction out of range")
+			}
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+		} else {
+			return nil, errors.New("invalid delta opcode 0")
+		}
+	}
+
+	if uint64(len(out)) != targetSize {
+		return nil, fmt.Errorf("delta target size mismatch: expected %d, got %d", targetSize, len(out))
+	}
+	return out, nil
+}
+
+// readDeltaVarint reads one of the source/target size varints at the start
+// of a delta stream: 7 bits per byte, little-endian, continuation via the
+// high bit.
+func readDeltaVarint(data []byte) (uint64, []byte, error) {
+	var value uint64
+	var shift uint
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		value |= uint64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			return value, data[i+1:], nil
+		}
+	}
+	return 0, nil, errors.New("truncated delta varint")
+}
+
+// packIndex is the parsed form of a .idx (version 2) file: a 256-entry
+// fanout table over the first SHA byte, and parallel sorted-SHA/offset
+// tables used to binary-search an object id to its byte offset in the
+// sibling .pack file.
+type packIndex struct {
+	packPath string
+	fanout   [256]uint32
+	shas     [][20]byte
+	offsets  []uint64
+}
+
+const packIdxMagic = "\xfftOc"
+
+func parsePackIndex(idxPath, packPath string) (*packIndex, error) {
+	raw, err := ioutil.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 8 || string(raw[:4]) != packIdxMagic {
+		return nil, fmt.Errorf("unsupported pack index format in %s (only v2 is supported)", idxPath)
+	}
+	version := binary.BigEndian.Uint32(raw[4:8])
+	if version != 2 {
+		return nil, fmt.Errorf("unsupported pack index version %d in %s", version, idxPath)
+	}
+
+	pi := &packIndex{packPath: packPath}
+
+	off := 8
+	for i := 0; i < 256; i++ {
+		pi.fanout[i] = binary.BigEndian.Uint32(raw[off : off+4])
+		off += 4
+	}
+	count := int(pi.fanout[255])
+
+	pi.shas = make([][20]byte, count)
+	for i := 0; i < count; i++ {
+		copy(pi.shas[i][:], raw[off:off+20])
+		off += 20
+	}
+
+	// crc32 table: one uint32 per object, not needed for reads.
+	off += count * 4
+
+	offsets32 := make([]uint32, count)
+	for i := 0; i < count; i++ {
+		offsets32[i] = binary.BigEndian.Uint32(raw[off : off+4])
+		off += 4
+	}
+
+	var largeOffsets []uint64
+	// Large-offset table only exists if some 32-bit offset has its MSB
+	// set (meaning it's really an index into this table); its length is
+	// the number of such entries.
+	largeCount := 0
+	for _, o := range offsets32 {
+		if o&0x80000000 != 0 {
+			largeCount++
+		}
+	}
+	if largeCount > 0 {
+		largeOffsets = make([]uint64, largeCount)
+		for i := 0; i < largeCount; i++ {
+			largeOffsets[i] = binary.BigEndian.Uint64(raw[off : off+8])
+			off += 8
+		}
+	}
+
+	pi.offsets = make([]uint64, count)
+	for i, o := range offsets32 {
+		if o&0x80000000 != 0 {
+			pi.offsets[i] = largeOffsets[o&0x7fffffff]
+		} else {
+			pi.offsets[i] = uint64(o)
+		}
+	}
+
+	return pi, nil
+}
+
+// find looks up sha using the fanout table to narrow to a single byte
+// bucket and a binary search within it.
+func (pi *packIndex) find(sha []byte) (uint64, bool) {
+	first := sha[0]
+	lo := 0
+	if first > 0 {
+		lo = int(pi.fanout[first-1])
+	}
+	hi := int(pi.fanout[first])
+
+	idx := sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(pi.shas[lo+i][:], sha) >= 0
+	})
+	idx += lo
+	if idx < hi && bytes.Equal(pi.shas[idx][:], sha) {
+		return pi.offsets[idx], true
+	}
+	return 0, false
+}
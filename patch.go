@@ -0,0 +1,245 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+)
+
+// PatchManager builds a unified patch from a subset of hunks or lines taken
+// from the diff between the workspace and the index, and applies it with
+// `git apply`. It is the building block for interactive, hunk-level staging.
+type PatchManager struct {
+	cli     CLI
+	patches []FilePatch
+	// selected maps a file path to the set of hunk indexes to include in
+	// full, and for partially selected hunks, the line indexes within them.
+	selected map[string]map[int][]int
+}
+
+// NewPatchManager creates a PatchManager over the current diff between the
+// workspace and the index.
+func NewPatchManager(cli CLI) (*PatchManager, error) {
+	patches, err := cli.DiffPatch("", "")
+	if err != nil {
+		return nil, err
+	}
+	return &PatchManager{
+		cli:      cli,
+		patches:  patches,
+		selected: map[string]map[int][]int{},
+	}, nil
+}
+
+// AddHunk selects an entire hunk of a file for the next apply.
+func (m *PatchManager) AddHunk(file string, hunkIndex int) error {
+	if _, err := m.findHunk(file, hunkIndex); err != nil {
+		return err
+	}
+	hunks, ok := m.selected[file]
+	if !ok {
+		hunks = map[int][]int{}
+		m.selected[file] = hunks
+	}
+	hunks[hunkIndex] = nil
+	return nil
+}
+
+// AddLines selects a subset of the added/removed lines within a hunk for the
+// next apply. Lines not listed are treated as if they were never part of the
+// hunk: omitted additions become context and omitted deletions are dropped.
+func (m *PatchManager) AddLines(file string, hunkIndex int, lineIndexes []int) error {
+	if _, err := m.findHunk(file, hunkIndex); err != nil {
+		return err
+	}
+	hunks, ok := m.selected[file]
+	if !ok {
+		hunks = map[int][]int{}
+		m.selected[file] = hunks
+	}
+	idx := append([]int{}, lineIndexes...)
+	sort.Ints(idx)
+	hunks[hunkIndex] = idx
+	return nil
+}
+
+// Reset clears all hunk and line selections.
+func (m *PatchManager) Reset() {
+	m.selected = map[string]map[int][]int{}
+}
+
+// RenderPatch builds a well-formed unified patch containing only the
+// selected hunks/lines, recomputing each hunk's header from the final line
+// counts.
+func (m *PatchManager) RenderPatch() ([]byte, error) {
+	var buf bytes.Buffer
+
+	for _, p := range m.patches {
+		hunkSel, ok := m.selected[m.key(p)]
+		if !ok {
+			continue
+		}
+
+		var hunks []Hunk
+		for hunkIndex, lineIdx := range hunkSel {
+			if hunkIndex < 0 || hunkIndex >= len(p.Hunks) {
+				continue
+			}
+			hunks = append(hunks, renderHunk(p.Hunks[hunkIndex], lineIdx))
+		}
+		if len(hunks) == 0 {
+			continue
+		}
+		sort.Slice(hunks, func(i, j int) bool { return hunks[i].OldStart < hunks[j].OldStart })
+
+		writeFileHeader(&buf, p)
+		for _, h := range hunks {
+			writeHunk(&buf, h)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ApplyToIndex stages the rendered patch by running `git apply --cached`.
+func (m *PatchManager) ApplyToIndex() error {
+	patch, err := m.RenderPatch()
+	if err != nil {
+		return err
+	}
+	return m.cli.runApply(patch, "--cached")
+}
+
+// ApplyReverse applies the rendered patch in reverse against the index, i.e.
+// unstages the selected hunks without touching the workspace.
+func (m *PatchManager) ApplyReverse() error {
+	patch, err := m.RenderPatch()
+	if err != nil {
+		return err
+	}
+	return m.cli.runApply(patch, "--reverse", "--cached")
+}
+
+func (m *PatchManager) key(p FilePatch) string {
+	if p.NewPath != "" {
+		return p.NewPath
+	}
+	return p.OldPath
+}
+
+func (m *PatchManager) findHunk(file string, hunkIndex int) (*FilePatch, error) {
+	for i := range m.patches {
+		if m.key(m.patches[i]) != file {
+			continue
+		}
+		if hunkIndex < 0 || hunkIndex >= len(m.patches[i].Hunks) {
+			return nil, fmt.Errorf("hunk index %d out of range for %s", hunkIndex, file)
+		}
+		return &m.patches[i], nil
+	}
+	return nil, fmt.Errorf("no diff found for %s", file)
+}
+
+// renderHunk rebuilds a hunk keeping only the selected line indexes: omitted
+// additions become context lines, omitted deletions are dropped entirely.
+func renderHunk(h Hunk, lineIdx []int) Hunk {
+	include := func(i int) bool {
+		if lineIdx == nil {
+			return true
+		}
+		for _, idx := range lineIdx {
+			if idx == i {
+				return true
+			}
+		}
+		return false
+	}
+
+	out := Hunk{OldStart: h.OldStart, NewStart: h.NewStart}
+	for i, l := range h.Lines {
+		switch l.Kind {
+		case LineContext:
+			out.Lines = append(out.Lines, l)
+			out.OldLines++
+			out.NewLines++
+		case LineDelete:
+			if include(i) {
+				out.Lines = append(out.Lines, l)
+				out.OldLines++
+			} else {
+				out.Lines = append(out.Lines, Line{Kind: LineContext, Text: l.Text, NoNewlineEOF: l.NoNewlineEOF})
+				out.OldLines++
+				out.NewLines++
+			}
+		case LineAdd:
+			if include(i) {
+				out.Lines = append(out.Lines, l)
+				out.NewLines++
+			}
+			// Omitted additions are dropped entirely.
+		}
+	}
+	return out
+}
+
+func writeFileHeader(buf *bytes.Buffer, p FilePatch) {
+	switch {
+	case p.OldPath == "":
+		mode := p.NewMode
+		if mode == "" {
+			mode = "100644"
+		}
+		fmt.Fprintf(buf, "diff --git a/%s b/%s\n", p.NewPath, p.NewPath)
+		fmt.Fprintf(buf, "new file mode %s\n", mode)
+		fmt.Fprintf(buf, "--- /dev/null\n")
+		fmt.Fprintf(buf, "+++ b/%s\n", p.NewPath)
+	case p.NewPath == "":
+		mode := p.OldMode
+		if mode == "" {
+			mode = "100644"
+		}
+		fmt.Fprintf(buf, "diff --git a/%s b/%s\n", p.OldPath, p.OldPath)
+		fmt.Fprintf(buf, "deleted file mode %s\n", mode)
+		fmt.Fprintf(buf, "--- a/%s\n", p.OldPath)
+		fmt.Fprintf(buf, "+++ /dev/null\n")
+	default:
+		fmt.Fprintf(buf, "diff --git a/%s b/%s\n", p.OldPath, p.NewPath)
+		fmt.Fprintf(buf, "--- a/%s\n", p.OldPath)
+		fmt.Fprintf(buf, "+++ b/%s\n", p.NewPath)
+	}
+}
+
+func writeHunk(buf *bytes.Buffer, h Hunk) {
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case LineAdd:
+			fmt.Fprintf(buf, "+%s\n", l.Text)
+		case LineDelete:
+			fmt.Fprintf(buf, "-%s\n", l.Text)
+		case LineContext:
+			fmt.Fprintf(buf, " %s\n", l.Text)
+		}
+		if l.NoNewlineEOF {
+			fmt.Fprintf(buf, "%s\n", noNewlineAtEOF)
+		}
+	}
+}
+
+// runApply pipes a rendered patch into `git apply <extraArgs...>`.
+func (c CLI) runApply(patch []byte, extraArgs ...string) error {
+	args := append([]string{"apply"}, extraArgs...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = c.path
+	cmd.Stdin = bytes.NewReader(patch)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("%s", stderr.String())
+		}
+		return err
+	}
+	return nil
+}
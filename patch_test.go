@@ -0,0 +1,145 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchManagerAddHunk(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one\ntwo\nthree\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one changed\ntwo\nthree changed\n"), 0744))
+
+	m, err := NewPatchManager(c)
+	assert.NoError(t, err)
+	assert.NoError(t, m.AddHunk("readme.md", 0))
+	assert.NoError(t, m.ApplyToIndex())
+
+	s, err := c.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, "readme.md", s[0].Path)
+	assert.Equal(t, StatusModified, s[0].Status)
+}
+
+func TestPatchManagerReset(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one changed\n"), 0744))
+
+	m, err := NewPatchManager(c)
+	assert.NoError(t, err)
+	assert.NoError(t, m.AddHunk("readme.md", 0))
+	m.Reset()
+
+	patch, err := m.RenderPatch()
+	assert.NoError(t, err)
+	assert.Empty(t, patch)
+}
+
+func TestPatchManagerStageDelete(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one\ntwo\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, os.Remove(path.Join(DataPath, "readme.md")))
+
+	m, err := NewPatchManager(c)
+	assert.NoError(t, err)
+	assert.NoError(t, m.AddHunk("readme.md", 0))
+	assert.NoError(t, m.ApplyToIndex())
+
+	s, err := c.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, "readme.md", s[0].Path)
+	assert.Equal(t, StatusDeleted, s[0].Status)
+}
+
+func TestPatchManagerApplyReverse(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one\ntwo\nthree\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one changed\ntwo\nthree changed\n"), 0744))
+
+	m, err := NewPatchManager(c)
+	assert.NoError(t, err)
+	assert.NoError(t, m.AddHunk("readme.md", 0))
+	assert.NoError(t, m.ApplyToIndex())
+	assert.NoError(t, m.ApplyReverse())
+
+	indexed, err := c.runCommandAndReadOutputAsString("show", ":readme.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "one\ntwo\nthree\n", indexed)
+
+	workspace, err := ioutil.ReadFile(path.Join(DataPath, "readme.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "one changed\ntwo\nthree changed\n", string(workspace))
+}
+
+func TestPatchManagerStageHunkNoTrailingNewline(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one\ntwo\nthree"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one\ntwo changed\nthree"), 0744))
+
+	m, err := NewPatchManager(c)
+	assert.NoError(t, err)
+	assert.NoError(t, m.AddHunk("readme.md", 0))
+	assert.NoError(t, m.ApplyToIndex())
+
+	indexed, err := c.runCommandAndReadOutputAsString("show", ":readme.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "one\ntwo changed\nthree", indexed)
+}
+
+func TestPatchManagerStageAdd(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("one\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "newfile.md"), []byte("hello\n"), 0744))
+	_, err := c.runCommand("add", "-N", "newfile.md")
+	assert.NoError(t, err)
+
+	m, err := NewPatchManager(c)
+	assert.NoError(t, err)
+	assert.NoError(t, m.AddHunk("newfile.md", 0))
+	assert.NoError(t, m.ApplyToIndex())
+
+	blob, err := c.runCommandAndReadOutputAsString("show", ":newfile.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", blob)
+}
@@ -0,0 +1,59 @@
+package git
+
+// Repository is the operation surface CLI has always exposed, pulled out
+// as an interface so that alternative backends can stand in for it. CLI
+// satisfies it by shelling out to the git binary; DotGit satisfies it by
+// reading the repository's objects and refs directly.
+type Repository interface {
+	Status() ([]StatusEntry, error)
+	IndexAll() error
+	Commit(message string) error
+	Log() ([]string, error)
+	Diff(from, to string) ([]string, error)
+	Blob(sha, path string) (string, error)
+	LsTree(sha string) ([]string, error)
+	RevParse(revisionOrPath string) (string, error)
+	MergeBase(first, second string) (string, error)
+	CreateBranch(name string) error
+	Checkout(path string) error
+	ConfigureUser(username, email string) error
+	Version() (string, error)
+}
+
+var _ Repository = CLI{}
+var _ Repository = &DotGit{}
+
+// repositoryConfig holds the options NewRepository assembles before picking
+// a backend.
+type repositoryConfig struct {
+	useDotGit bool
+}
+
+// Option configures the backend NewRepository constructs.
+type Option func(*repositoryConfig)
+
+// WithDotGit selects the DotGit backend, which reads loose objects and
+// packfiles directly instead of invoking the git binary. Use it in
+// container/CI environments without a git binary, or to get faster reads
+// on hot paths like directory listings.
+func WithDotGit() Option {
+	return func(c *repositoryConfig) {
+		c.useDotGit = true
+	}
+}
+
+// NewRepository opens the repository at path with the backend selected by
+// opts, defaulting to CLI.
+func NewRepository(path string, opts ...Option) (Repository, error) {
+	cfg := &repositoryConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.useDotGit {
+		return NewDotGit(path)
+	}
+
+	cli := NewCLI(path)
+	return cli, nil
+}
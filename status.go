@@ -0,0 +1,302 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SubmoduleState captures the submodule substate bits reported by porcelain
+// v2 for an entry whose path is a submodule.
+type SubmoduleState struct {
+	IsSubmodule         bool
+	HasNewCommits       bool
+	HasModifiedContent  bool
+	HasUntrackedContent bool
+}
+
+// StatusEntryV2 is a single entry reported by `git status --porcelain=v2`.
+type StatusEntryV2 struct {
+	// IndexStatus and WorktreeStatus are the two single-character codes
+	// that make up the XY pair, e.g. "A" and "." after `git add`.
+	IndexStatus    string
+	WorktreeStatus string
+	Submodule      SubmoduleState
+
+	HeadMode     string
+	IndexMode    string
+	WorktreeMode string
+	HeadOID      string
+	IndexOID     string
+
+	Path string
+	// OldPath/NewPath and Similarity are only populated for rename/copy
+	// entries (XY starts with R or C).
+	OldPath    string
+	NewPath    string
+	Similarity int
+	IsCopy     bool
+
+	// Unmerged entries additionally report the three merge stages.
+	IsUnmerged bool
+	Stage1Mode string
+	Stage2Mode string
+	Stage3Mode string
+	Stage1OID  string
+	Stage2OID  string
+	Stage3OID  string
+}
+
+// BranchStatus is the `# branch.*` header reported by porcelain v2.
+type BranchStatus struct {
+	Name     string
+	OID      string
+	Upstream string
+	Ahead    int
+	Behind   int
+}
+
+// RepoStatus is the parsed result of `git status --porcelain=v2`.
+type RepoStatus struct {
+	Branch  BranchStatus
+	Entries []StatusEntryV2
+}
+
+// StatusV2 runs `git status --porcelain=v2 -z --branch --untracked-files=all
+// --ignored=matching` and returns the full, unambiguous status of the
+// repository: branch/upstream tracking info, and entries with separate
+// index/worktree state, rename/copy similarity, submodule substate, and mode
+// transitions. Unlike Status, it never drops copy ("C") or ignored ("!")
+// entries, and the -z NUL-terminated format removes the quoting ambiguity
+// the short format has with filenames containing spaces or unicode.
+func (c CLI) StatusV2() (*RepoStatus, error) {
+	buf, err := c.runCommandAndReadOutputAsBytes("status", "--porcelain=v2", "-z", "--branch", "--untracked-files=all", "--ignored=matching")
+	if err != nil {
+		return nil, err
+	}
+	return parseStatusV2(buf)
+}
+
+// Status runs git status command. It is a thin compatibility shim over
+// StatusV2, preferring the index state over the worktree state for files
+// that differ in both, matching the short-format precedence this method has
+// always had.
+func (c CLI) Status() ([]StatusEntry, error) {
+	rs, err := c.StatusV2()
+	if err != nil {
+		return nil, err
+	}
+	return statusEntriesFromV2(rs), nil
+}
+
+// statusEntriesFromV2 downgrades a RepoStatus to the legacy []StatusEntry
+// shape, preferring the index state over the worktree state for files that
+// differ in both, matching the short-format precedence Status has always
+// had. Entries with a status code the legacy Status type has no equivalent
+// for (e.g. copies) are dropped.
+func statusEntriesFromV2(rs *RepoStatus) []StatusEntry {
+	r := make([]StatusEntry, 0, len(rs.Entries))
+	for _, e := range rs.Entries {
+		code := e.IndexStatus
+		if code == "." || code == "" {
+			code = e.WorktreeStatus
+		}
+		if code == "?" {
+			code = "??"
+		}
+		status, ok := statusTable[code]
+		if !ok {
+			continue
+		}
+		path := e.Path
+		if path == "" {
+			path = e.NewPath
+		}
+		r = append(r, StatusEntry{
+			Status: status,
+			Path:   path,
+		})
+	}
+	return r
+}
+
+func parseStatusV2(buf []byte) (*RepoStatus, error) {
+	rs := &RepoStatus{}
+
+	tokens := splitNUL(buf)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(tok, "# branch.oid "):
+			rs.Branch.OID = strings.TrimPrefix(tok, "# branch.oid ")
+		case strings.HasPrefix(tok, "# branch.head "):
+			rs.Branch.Name = strings.TrimPrefix(tok, "# branch.head ")
+		case strings.HasPrefix(tok, "# branch.upstream "):
+			rs.Branch.Upstream = strings.TrimPrefix(tok, "# branch.upstream ")
+		case strings.HasPrefix(tok, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(tok, "# branch.ab "))
+			for _, f := range fields {
+				n, err := strconv.Atoi(f[1:])
+				if err != nil {
+					return nil, fmt.Errorf("malformed branch.ab line %q: %w", tok, err)
+				}
+				if strings.HasPrefix(f, "+") {
+					rs.Branch.Ahead = n
+				} else if strings.HasPrefix(f, "-") {
+					rs.Branch.Behind = n
+				}
+			}
+		case strings.HasPrefix(tok, "1 "):
+			entry, err := parseOrdinaryEntry(tok)
+			if err != nil {
+				return nil, err
+			}
+			rs.Entries = append(rs.Entries, *entry)
+		case strings.HasPrefix(tok, "2 "):
+			entry, err := parseRenameEntry(tok)
+			if err != nil {
+				return nil, err
+			}
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("missing origPath for rename entry %q", tok)
+			}
+			i++
+			entry.OldPath = tokens[i]
+			rs.Entries = append(rs.Entries, *entry)
+		case strings.HasPrefix(tok, "u "):
+			entry, err := parseUnmergedEntry(tok)
+			if err != nil {
+				return nil, err
+			}
+			rs.Entries = append(rs.Entries, *entry)
+		case strings.HasPrefix(tok, "? "):
+			rs.Entries = append(rs.Entries, StatusEntryV2{
+				IndexStatus:    "?",
+				WorktreeStatus: "?",
+				Path:           strings.TrimPrefix(tok, "? "),
+			})
+		case strings.HasPrefix(tok, "! "):
+			rs.Entries = append(rs.Entries, StatusEntryV2{
+				IndexStatus:    "!",
+				WorktreeStatus: "!",
+				Path:           strings.TrimPrefix(tok, "! "),
+			})
+		}
+	}
+
+	return rs, nil
+}
+
+// splitNUL splits a NUL-terminated byte stream into its tokens, dropping the
+// trailing empty token produced by the final terminator.
+func splitNUL(buf []byte) []string {
+	parts := bytes.Split(buf, []byte{0})
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		out = append(out, string(p))
+	}
+	if len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func parseSubmodule(field string) SubmoduleState {
+	if len(field) != 4 {
+		return SubmoduleState{}
+	}
+	return SubmoduleState{
+		IsSubmodule:         field[0] == 'S',
+		HasNewCommits:       field[1] == 'C',
+		HasModifiedContent:  field[2] == 'M',
+		HasUntrackedContent: field[3] == 'U',
+	}
+}
+
+// parseOrdinaryEntry parses a "1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>" record.
+func parseOrdinaryEntry(tok string) (*StatusEntryV2, error) {
+	fields := strings.SplitN(tok, " ", 9)
+	if len(fields) != 9 {
+		return nil, fmt.Errorf("malformed status entry %q", tok)
+	}
+	xy := fields[1]
+	if len(xy) != 2 {
+		return nil, fmt.Errorf("malformed XY code %q", xy)
+	}
+	return &StatusEntryV2{
+		IndexStatus:    string(xy[0]),
+		WorktreeStatus: string(xy[1]),
+		Submodule:      parseSubmodule(fields[2]),
+		HeadMode:       fields[3],
+		IndexMode:      fields[4],
+		WorktreeMode:   fields[5],
+		HeadOID:        fields[6],
+		IndexOID:       fields[7],
+		Path:           fields[8],
+	}, nil
+}
+
+// parseRenameEntry parses a "2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X-score> <path>"
+// record; the origPath that follows (NUL-delimited) is filled in by the caller.
+func parseRenameEntry(tok string) (*StatusEntryV2, error) {
+	fields := strings.SplitN(tok, " ", 10)
+	if len(fields) != 10 {
+		return nil, fmt.Errorf("malformed rename entry %q", tok)
+	}
+	xy := fields[1]
+	if len(xy) != 2 {
+		return nil, fmt.Errorf("malformed XY code %q", xy)
+	}
+	score := fields[8]
+	isCopy := strings.HasPrefix(score, "C")
+	similarity := 0
+	if len(score) > 1 {
+		if n, err := strconv.Atoi(score[1:]); err == nil {
+			similarity = n
+		}
+	}
+	return &StatusEntryV2{
+		IndexStatus:    string(xy[0]),
+		WorktreeStatus: string(xy[1]),
+		Submodule:      parseSubmodule(fields[2]),
+		HeadMode:       fields[3],
+		IndexMode:      fields[4],
+		WorktreeMode:   fields[5],
+		HeadOID:        fields[6],
+		IndexOID:       fields[7],
+		Similarity:     similarity,
+		IsCopy:         isCopy,
+		NewPath:        fields[9],
+	}, nil
+}
+
+// parseUnmergedEntry parses a "u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>" record.
+func parseUnmergedEntry(tok string) (*StatusEntryV2, error) {
+	fields := strings.SplitN(tok, " ", 11)
+	if len(fields) != 11 {
+		return nil, fmt.Errorf("malformed unmerged entry %q", tok)
+	}
+	xy := fields[1]
+	if len(xy) != 2 {
+		return nil, fmt.Errorf("malformed XY code %q", xy)
+	}
+	return &StatusEntryV2{
+		IndexStatus:    string(xy[0]),
+		WorktreeStatus: string(xy[1]),
+		Submodule:      parseSubmodule(fields[2]),
+		IsUnmerged:     true,
+		Stage1Mode:     fields[3],
+		Stage2Mode:     fields[4],
+		Stage3Mode:     fields[5],
+		WorktreeMode:   fields[6],
+		Stage1OID:      fields[7],
+		Stage2OID:      fields[8],
+		Stage3OID:      fields[9],
+		Path:           fields[10],
+	}, nil
+}
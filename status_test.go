@@ -0,0 +1,69 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusV2Untracked(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+
+	rs, err := c.StatusV2()
+	assert.NoError(t, err)
+	assert.Len(t, rs.Entries, 1)
+	assert.Equal(t, "readme.md", rs.Entries[0].Path)
+	assert.Equal(t, "?", rs.Entries[0].IndexStatus)
+	assert.Equal(t, "?", rs.Entries[0].WorktreeStatus)
+}
+
+func TestStatusV2Rename(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.ConfigureUser("barry", "barry@starlabs.org"))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey, this is a fairly long line of text\n"), 0744))
+	assert.NoError(t, c.IndexAll())
+	assert.NoError(t, c.Commit("first"))
+
+	assert.NoError(t, os.Rename(path.Join(DataPath, "readme.md"), path.Join(DataPath, "readme2.md")))
+	assert.NoError(t, c.IndexAll())
+
+	rs, err := c.StatusV2()
+	assert.NoError(t, err)
+	assert.Len(t, rs.Entries, 1)
+	e := rs.Entries[0]
+	assert.Equal(t, "R", e.IndexStatus)
+	assert.Equal(t, "readme.md", e.OldPath)
+	assert.Equal(t, "readme2.md", e.NewPath)
+	assert.True(t, e.Similarity > 0)
+}
+
+func TestStatusV2Ignored(t *testing.T) {
+	setup()
+	c := NewCLI(DataPath)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, ".gitignore"), []byte("ignored.md\n"), 0744))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "ignored.md"), []byte("#hey"), 0744))
+	assert.NoError(t, ioutil.WriteFile(path.Join(DataPath, "readme.md"), []byte("#hey"), 0744))
+
+	rs, err := c.StatusV2()
+	assert.NoError(t, err)
+	assert.Len(t, rs.Entries, 3)
+
+	var ignored *StatusEntryV2
+	for i := range rs.Entries {
+		if rs.Entries[i].Path == "ignored.md" {
+			ignored = &rs.Entries[i]
+		}
+	}
+	assert.NotNil(t, ignored)
+	assert.Equal(t, "!", ignored.IndexStatus)
+	assert.Equal(t, "!", ignored.WorktreeStatus)
+}